@@ -1,13 +1,17 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/trsnaqe/protondb-api/pkg/server"
+	"github.com/trsnaqe/protondb-api/pkg/services/analytics_service"
 	"github.com/trsnaqe/protondb-api/pkg/services/background_services"
+	"github.com/trsnaqe/protondb-api/pkg/services/games_service"
 	"github.com/trsnaqe/protondb-api/pkg/storage"
 )
 
@@ -21,9 +25,61 @@ func main() {
 			log.Fatalf("Error loading .env file")
 		}
 	}
-	err := storage.ConnectDB()
-	if err != nil {
-		panic(err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrate(os.Args[2:])
+		return
+	}
+	if os.Getenv("STORAGE") == "memory" {
+		// Keep everything in memory, useful for tests and local development without MongoDB
+		log.Println("Using in-memory storage")
+		storage.SetStore(storage.NewMemoryStore())
+	} else {
+		err := storage.ConnectDB()
+		if err != nil {
+			panic(err)
+		}
+		// Deployments migrating with the migrate command before rolling out can turn this off
+		if os.Getenv("AUTO_MIGRATE") != "false" {
+			if err := storage.RunMigrations(false); err != nil {
+				log.Fatalf("Error running migrations: %v", err)
+			}
+		}
+		storage.SetStore(storage.NewMongoStore())
+	}
+	if err := games_service.RebuildSearchIndex(); err != nil {
+		log.Printf("Error building the search index: %v", err)
+	}
+	// Regression thresholds, unset or invalid values keep the defaults
+	regressionConfig := analytics_service.RegressionConfig{}
+	if minReports, err := strconv.ParseInt(os.Getenv("REGRESSION_MIN_REPORTS"), 10, 64); err == nil {
+		regressionConfig.MinReports = minReports
+	}
+	if minDrop, err := strconv.ParseFloat(os.Getenv("REGRESSION_MIN_DROP"), 64); err == nil {
+		regressionConfig.MinDrop = minDrop
+	}
+	if maxPValue, err := strconv.ParseFloat(os.Getenv("REGRESSION_MAX_P_VALUE"), 64); err == nil {
+		regressionConfig.MaxPValue = maxPValue
+	}
+	analytics_service.SetRegressionConfig(regressionConfig)
+	go func() {
+		// Computing the dataset stats of a large database takes a while, serve requests meanwhile
+		if err := analytics_service.EnsureDatasetStats(); err != nil {
+			log.Printf("Error computing the dataset stats: %v", err)
+		}
+		if err := analytics_service.EnsureRegressions(); err != nil {
+			log.Printf("Error detecting regressions: %v", err)
+		}
+	}()
+	if reportsDir := os.Getenv("REPORTS_DIR"); reportsDir != "" {
+		// Read the dumps from a local directory instead of GitHub, e.g. on an offline machine
+		log.Println("Reading report dumps from", reportsDir)
+		background_services.SetReportSource(background_services.NewLocalSource(reportsDir))
+	}
+	if batchSize, err := strconv.Atoi(os.Getenv("INGEST_BATCH_SIZE")); err == nil {
+		background_services.SetBatchSize(batchSize)
+	}
+	if workers, err := strconv.Atoi(os.Getenv("INGEST_WORKERS")); err == nil {
+		background_services.SetWorkerCount(workers)
 	}
 	background_services.SetUpdateInterval(updateInterval)
 	if updateInterval > 0 { // Don't start the goroutine if updateInterval is zero
@@ -37,3 +93,25 @@ func main() {
 	server := server.NewServer()
 	server.Run(":" + port)
 }
+
+// migrate applies the pending database migrations and exits, or only lists them with -dry-run
+func migrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "list the pending migrations without applying them")
+	flags.Parse(args)
+
+	if os.Getenv("DB_URI") == "" {
+		// Load .env file when the database is not configured in the environment
+		if err := godotenv.Load(); err != nil {
+			log.Fatalf("Error loading .env file")
+		}
+	}
+	if err := storage.ConnectDB(); err != nil {
+		log.Fatalf("Error connecting to the database: %v", err)
+	}
+	defer storage.CloseDB()
+
+	if err := storage.RunMigrations(*dryRun); err != nil {
+		log.Fatalf("Error running migrations: %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package games_controller
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -9,35 +11,42 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/trsnaqe/protondb-api/pkg/constants"
+	"github.com/trsnaqe/protondb-api/pkg/pagination"
 	"github.com/trsnaqe/protondb-api/pkg/services/games_service"
+	"github.com/trsnaqe/protondb-api/pkg/services/reports_service"
 )
 
-// Endpoint to retrieve all games.
+// Endpoint to retrieve all games, a page at a time.
 func GetAllGamesHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	/*
-		games, err := games_service.GetAllGames()
-		if err != nil {
-			http.Error(w, "Failed to retrieve games", http.StatusInternalServerError)
-			return
-		}
-		if(len(games) == 0) {
-			http.Error(w, "No games found", http.StatusNotFound)
-			return
-		}
 
-		encoder := json.NewEncoder(w)
-		err = encoder.Encode(games)
-		if err != nil {
-			http.Error(w, "Failed to encode games", http.StatusInternalServerError)
-		}
-	*/
-	message := "Status Code 503: Service Unavailable.\n\n" +
-		"This endpoint is currently unavailable as the server cannot handle the request. Please try again later or consider supporting the project by buying me a coffee. Your support helps keep this service running.\n\n" +
-		"Support the project by buying me a coffee at: https://www.buymeacoffee.com/trsnaqe"
+	page, err := pagination.ParseParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	w.WriteHeader(http.StatusServiceUnavailable)
-	w.Write([]byte(message))
+	writeGamesPage(w, r, page)
+}
+
+// writeGamesPage encodes a page of all games and links the next page
+func writeGamesPage(w http.ResponseWriter, r *http.Request, page pagination.Params) {
+	games, next, err := games_service.ListGames(page)
+	if err != nil {
+		http.Error(w, "Failed to retrieve games", http.StatusInternalServerError)
+		return
+	}
+	if len(games) == 0 && page.Cursor == nil {
+		http.Error(w, "No games found", http.StatusNotFound)
+		return
+	}
+
+	pagination.SetNext(w, r, next)
+
+	err = json.NewEncoder(w).Encode(games)
+	if err != nil {
+		http.Error(w, "Failed to encode games", http.StatusInternalServerError)
+	}
 }
 
 // Endpoint to search games by title.
@@ -62,10 +71,6 @@ func SearchGameByTitleHandler(w http.ResponseWriter, r *http.Request) {
 			precision = float64(parsedPrecision)
 		case "title":
 			title = strings.ToLower(values[0])
-			if len(title) < 5 {
-				http.Error(w, "Title query must be at least 5 characters long ", http.StatusBadRequest)
-				return
-			}
 		}
 	}
 
@@ -107,6 +112,7 @@ func GetGameByAppIDHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(game)
 }
 
+// Endpoint to retrieve the summary of a game, computed from its stored reports.
 func GetGameSummaryHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -119,8 +125,27 @@ func GetGameSummaryHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to retrieve game summary", http.StatusInternalServerError)
 		return
 	}
+	if summary == nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	var response interface{} = summary
+	if compare, _ := strconv.ParseBool(r.URL.Query().Get("compare")); compare {
+		// Put the summary protondb.com serves next to ours, reporting its error instead if it is unreachable
+		comparison := map[string]interface{}{"local": summary}
+		protondbSummary, err := games_service.GetProtonDBGameSummary(appID)
+		if err != nil {
+			log.Printf("Error getting protondb.com game summary: %v", err)
+			comparison["protondb"] = nil
+			comparison["protondbError"] = err.Error()
+		} else {
+			comparison["protondb"] = protondbSummary
+		}
+		response = comparison
+	}
 
-	err = json.NewEncoder(w).Encode(summary)
+	err = json.NewEncoder(w).Encode(response)
 	if err != nil {
 		log.Printf("Error encoding game summary response: %v", err)
 		http.Error(w, "Failed to encode game summary response", http.StatusInternalServerError)
@@ -129,10 +154,162 @@ func GetGameSummaryHandler(w http.ResponseWriter, r *http.Request) {
 
 //v2 endpoints
 
+const (
+	defaultStatsTop = 10
+	maxStatsTop     = 100
+)
+
+// Endpoint to retrieve the statistics of the reports of a game.
+func GetGameStatsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	appID := mux.Vars(r)["appId"]
+
+	top := defaultStatsTop
+	if value := r.URL.Query().Get("top"); value != "" {
+		parsedTop, err := strconv.Atoi(value)
+		if err != nil || parsedTop < 1 || parsedTop > maxStatsTop {
+			http.Error(w, fmt.Sprintf("top must be a number between 1 and %d", maxStatsTop), http.StatusBadRequest)
+			return
+		}
+		top = parsedTop
+	}
+
+	stats, err := games_service.GetGameStats(appID, top)
+	if err != nil {
+		log.Printf("Error getting game stats: %v", err)
+		http.Error(w, "Failed to retrieve game stats", http.StatusInternalServerError)
+		return
+	}
+	if stats == nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(stats)
+	if err != nil {
+		http.Error(w, "Failed to encode game stats", http.StatusInternalServerError)
+	}
+}
+
+// Endpoint to retrieve how a game runs on each Proton build and release, and the release it works since.
+func GetGameProtonHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	appID := mux.Vars(r)["appId"]
+
+	compatibility, err := games_service.GetProtonCompatibility(appID)
+	if err != nil {
+		log.Printf("Error getting Proton compatibility: %v", err)
+		http.Error(w, "Failed to retrieve Proton compatibility", http.StatusInternalServerError)
+		return
+	}
+	if compatibility == nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(compatibility)
+	if err != nil {
+		http.Error(w, "Failed to encode Proton compatibility", http.StatusInternalServerError)
+	}
+}
+
+// Endpoint to retrieve the reports of a game and their ratings counted per day, week, month, quarter or year.
+func GetGameTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	appID := mux.Vars(r)["appId"]
+
+	bucket := games_service.BucketMonth
+	if value := strings.ToLower(r.URL.Query().Get("bucket")); value != "" {
+		if !isOneOf(value, games_service.TimelineBuckets) {
+			http.Error(w, fmt.Sprintf("Bucket must be one of %s", strings.Join(games_service.TimelineBuckets, ", ")), http.StatusBadRequest)
+			return
+		}
+		bucket = value
+	}
+
+	timeline, err := games_service.GetGameTimeline(appID, bucket)
+	if err != nil {
+		log.Printf("Error getting game timeline: %v", err)
+		http.Error(w, "Failed to retrieve game timeline", http.StatusInternalServerError)
+		return
+	}
+	if timeline == nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(timeline)
+	if err != nil {
+		http.Error(w, "Failed to encode game timeline", http.StatusInternalServerError)
+	}
+}
+
+const (
+	defaultMatchLimit = 10
+	maxMatchLimit     = 100
+)
+
+// Endpoint to find the reports of a game from setups similar to the one queried and predict its rating on it.
+func MatchGameSetupHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	appID := mux.Vars(r)["appId"]
+
+	var setup games_service.Setup
+	limit := defaultMatchLimit
+	for key, values := range r.URL.Query() {
+		value := strings.TrimSpace(values[0])
+		switch strings.ToLower(key) {
+		case "gpu":
+			setup.GPU = value
+		case "driver", "gpudriver":
+			setup.GPUDriver = value
+		case "kernel":
+			setup.Kernel = value
+		case "distro", "os":
+			setup.Distro = value
+		case "proton", "protonversion":
+			setup.ProtonVersion = value
+		case "limit":
+			parsedLimit, err := strconv.Atoi(value)
+			if err != nil || parsedLimit < 1 || parsedLimit > maxMatchLimit {
+				http.Error(w, fmt.Sprintf("limit must be a number between 1 and %d", maxMatchLimit), http.StatusBadRequest)
+				return
+			}
+			limit = parsedLimit
+		}
+	}
+	if setup.IsEmpty() {
+		http.Error(w, "At least one of gpu, driver, kernel, distro or protonVersion is required", http.StatusBadRequest)
+		return
+	}
+
+	match, err := games_service.MatchSetup(appID, setup, limit)
+	if err != nil {
+		log.Printf("Error matching game setup: %v", err)
+		http.Error(w, "Failed to match setup", http.StatusInternalServerError)
+		return
+	}
+	if match == nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(match)
+	if err != nil {
+		http.Error(w, "Failed to encode setup match", http.StatusInternalServerError)
+	}
+}
+
 func GetGameByQueryHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var appId, title string
+	var reportsPerGame int
+	var opts reports_service.ReportOptions
 	precision := constants.DEFAULT_SEARCH_PRECISION
 
 	for key, values := range r.URL.Query() {
@@ -142,10 +319,24 @@ func GetGameByQueryHandler(w http.ResponseWriter, r *http.Request) {
 			appId = strings.ToLower(values[0])
 		case "title":
 			title = strings.ToLower(values[0])
-			if len(title) < 5 {
-				http.Error(w, "Title query must be at least 5 characters long ", http.StatusBadRequest)
+		case "reports":
+			parsedReports, err := strconv.Atoi(values[0])
+			if err != nil || parsedReports < 1 || parsedReports > pagination.MaxLimit {
+				http.Error(w, fmt.Sprintf("Reports value must be a number between 1 and %d", pagination.MaxLimit), http.StatusBadRequest)
 				return
 			}
+			reportsPerGame = parsedReports
+		case "versioned":
+			opts.Versioned = values[0] == "true" || values[0] == "1"
+		case "raw":
+			opts.Raw = values[0] == "true" || values[0] == "1"
+		case "version":
+			switch values[0] {
+			case "1":
+				opts.Version = "V1"
+			case "2":
+				opts.Version = "V2"
+			}
 		case "precision":
 			parsedPrecision, err := strconv.ParseFloat(values[0], 32)
 			if err != nil {
@@ -161,28 +352,26 @@ func GetGameByQueryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if appId == "" && title == "" {
-		/*
-			games, err := games_service.GetAllGames()
-			if err != nil {
-				http.Error(w, "Failed to retrieve games", http.StatusInternalServerError)
-				return
-			}
+		page, err := pagination.ParseParams(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-			encoder := json.NewEncoder(w)
-			err = encoder.Encode(games)
-			if err != nil {
-				http.Error(w, "Failed to encode games", http.StatusInternalServerError)
-			}
-		*/
-		message := "Status Code 503: Service Unavailable.\n\n" +
-			"This endpoint is currently unavailable as the server cannot handle the request. Please try again later or consider supporting the project by buying me a coffee. Your support helps keep this service running.\n\n" +
-			"Support the project by buying me a coffee at: https://www.buymeacoffee.com/trsnaqe"
-		w.WriteHeader(http.StatusServiceUnavailable)
-		w.Write([]byte(message))
+		writeGamesPage(w, r, page)
+		return
+	}
+
+	if appId == "" && reportsPerGame > 0 {
+		writeGamesWithReports(w, r, title, precision, reportsPerGame, opts)
 		return
 	}
 
 	games, err := games_service.GetGameByQuery(appId, title, precision)
+	if errors.Is(err, games_service.ErrGameNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -199,3 +388,80 @@ func GetGameByQueryHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to encode games", http.StatusInternalServerError)
 	}
 }
+
+// writeGamesWithReports encodes a page of the games matching the title, each with some of its reports
+func writeGamesWithReports(w http.ResponseWriter, r *http.Request, title string, precision float64, reportsPerGame int, opts reports_service.ReportOptions) {
+	page, err := pagination.ParseParams(r.URL.Query())
+	if err == nil {
+		err = page.CheckSort("score")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	games, next, err := reports_service.GetGamesWithReportsByTitle(title, precision, reportsPerGame, opts, page)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to retrieve games: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if len(games) == 0 && page.Cursor == nil {
+		http.Error(w, "No games found matching the query", http.StatusNotFound)
+		return
+	}
+
+	pagination.SetNext(w, r, next)
+
+	err = json.NewEncoder(w).Encode(games)
+	if err != nil {
+		http.Error(w, "Failed to encode games", http.StatusInternalServerError)
+	}
+}
+
+const (
+	defaultSuggestions = 10
+	maxSuggestions     = 50
+)
+
+// Endpoint suggesting games as a title is typed, from the in-memory search index.
+func SuggestGamesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		http.Error(w, "q query parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultSuggestions
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsedLimit, err := strconv.Atoi(value)
+		if err != nil || parsedLimit < 1 || parsedLimit > maxSuggestions {
+			http.Error(w, fmt.Sprintf("limit must be a number between 1 and %d", maxSuggestions), http.StatusBadRequest)
+			return
+		}
+		limit = parsedLimit
+	}
+
+	// No suggestions is a normal answer while typing, not a missing resource
+	suggestions := games_service.SuggestGames(query, limit)
+
+	err := json.NewEncoder(w).Encode(suggestions)
+	if err != nil {
+		http.Error(w, "Failed to encode suggestions", http.StatusInternalServerError)
+	}
+}
+
+// isOneOf tells if value is one of valid
+func isOneOf(value string, valid []string) bool {
+	for _, candidate := range valid {
+		if value == candidate {
+			return true
+		}
+	}
+	return false
+}
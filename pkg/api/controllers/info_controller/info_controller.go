@@ -6,14 +6,21 @@ import "net/http"
 func ListAPIEndpointsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain")
 	endpoints := []string{
-		"/api/games (GET): Get all games*",
+		"/api/games (GET): Get all games",
 		"/api/games/{gameId} (GET): Get a game by gameId",
-		"/api/games/{gameId}/summary (GET): Get tiers by gameId, fetched from protondb directly",
-		"/api/reports (GET): Retrieve reports, add ?versioned=true for versioned data*",
-		"/api/reports/{gameId} (GET): Get reports by gameId, add ?versioned=true for versioned data",
-		"/api/stats (GET): Get stats of the API",
-		"/api/v2/games (GET): Get games by query, add ?title or appid to search by title or appid respectively. Title text search accuracy can be adjusted by &precision=(min 0). Appid supersedes the title query",
-		"/api/v2/reports (GET): Get reports by query, add ?versioned=true for versioned data, version= 1 or 2 to filter by version; title or appid to search by title or appid respectively. Title text search accuracy can be adjusted by &precision=(min 0). Appid supersedes the title query",
+		"/api/games/{gameId}/summary (GET): Get tiers by gameId, computed from the stored reports, add ?compare=true to also get the summary from protondb",
+		"/api/reports (GET): Retrieve all reports, add ?versioned=true for versioned data, ?raw=true for the data as published by ProtonDB",
+		"/api/reports/{gameId} (GET): Get reports by gameId, add ?versioned=true for versioned data, ?raw=true for the data as published by ProtonDB",
+		"/api/stats (GET): Get stats of the API, request counts and analytics over the whole dataset computed after each ingestion",
+		"/api/v2/games (GET): Get games by query, add ?title or appid to search by title or appid respectively. Title text search accuracy can be adjusted by &precision=(min 0). Appid supersedes the title query. Add &reports=N to a title search to get up to N reports with each game",
+		"/api/v2/games/suggest (GET): Suggest games as a title is typed, add ?q= with the start of the title and &limit=N (default 10, max 50) for the number of suggestions",
+		"/api/v2/games/{appId}/stats (GET): Get the reports of a game counted per month, rating, Proton version, GPU vendor, distro, tweak and launch options, add ?top=N (default 10, max 100) for the number of most common values listed",
+		"/api/v2/games/{appId}/match (GET): Get the reports of a game from the setups most similar to yours and the rating predicted from them, describe your setup with any of ?gpu, driver, kernel, distro and protonVersion, add &limit=N (default 10, max 100) for the number of reports",
+		"/api/v2/games/{appId}/proton (GET): Get the reports of a game counted per Proton build and release, with the share of working reports and the release the game works since",
+		"/api/v2/games/{appId}/timeline (GET): Get the reports of a game and their ratings counted per period, from the first report to the last, add ?bucket=day|week|month|quarter|year (default month)",
+		"/api/v2/games/{appId}/regressions (GET): Get the significant rating drops of a game between consecutive Proton, driver or kernel versions, largest first",
+		"/api/v2/regressions (GET): Get the significant rating drops detected across all games between consecutive Proton, driver or kernel versions, largest first, filter with component, group, minReports, minDrop and maxPValue",
+		"/api/v2/reports (GET): Get reports by query, add ?versioned=true for versioned data, raw=true for the data as published by ProtonDB, version= 1 or 2 to filter by version; title or appid to search by title or appid respectively. Title text search accuracy can be adjusted by &precision=(min 0). Appid supersedes the title query. Filter with gpuVendor, gpu, gpuDriver, os, protonVersion, kernelMin, kernelMax, rating (comma-separated), verdict, from and to, and on the parsed hardware with gpuFamily, gpuModel, driverVendor, driverMin, driverMax, distroName, distroVersion, cpuVendor, cpuModel, ramMin and ramMax. Compare Proton versions with protonVersion>=8.0 (also >, <, <=, ==) and pick builds with protonBuild=official,ge,experimental,tkg,other. Order with sort=timestamp|rating|protonVersion (prefix - for descending) and pick fields with fields=timestamp,rating",
 	}
 	response := "Available endpoints in the protondb.solidet.com:\n\n"
 
@@ -21,7 +28,7 @@ func ListAPIEndpointsHandler(w http.ResponseWriter, r *http.Request) {
 		response += endpoint + "\n"
 	}
 
-	response += "\n*Had to disable the /api/games and /api/reports endpoints because the dataset is large and it costs a lot to leave those endpoints open. I want to enable them again in the future, but I need to find support to help me host this.\n\n"
+	response += "\nLists are paginated: use ?limit= (default 100, max 1000) and follow the Link header, or pass the X-Next-Cursor header back as ?cursor=, to get the next page.\n\n"
 
 	openSourceLink := "You can find the source code for this project on GitHub:\nhttps://github.com/Trsnaqe/protondb-community-api\n\n"
 
@@ -0,0 +1,131 @@
+package regressions_controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/pagination"
+	"github.com/trsnaqe/protondb-api/pkg/services/analytics_service"
+	"github.com/trsnaqe/protondb-api/pkg/storage"
+)
+
+var validComponents = []string{models.RegressionProton, models.RegressionDriver, models.RegressionKernel}
+
+// Endpoint to retrieve the rating regressions detected across all games, largest drop first, a page at a time.
+func GetRegressionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query, err := parseRegressionQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, err := pagination.ParseParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	regressions, next, err := analytics_service.ListRegressions(query, page)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Println("Error listing regressions:", err)
+		http.Error(w, "Failed to retrieve regressions", http.StatusInternalServerError)
+		return
+	}
+
+	pagination.SetNext(w, r, next)
+
+	err = json.NewEncoder(w).Encode(regressions)
+	if err != nil {
+		http.Error(w, "Failed to encode regressions", http.StatusInternalServerError)
+	}
+}
+
+// Endpoint to retrieve the rating regressions detected for a game, largest drop first.
+func GetGameRegressionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	appID := mux.Vars(r)["appId"]
+
+	query, err := parseRegressionQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	regressions, err := analytics_service.GetGameRegressions(appID, query)
+	if err != nil {
+		log.Println("Error getting game regressions:", err)
+		http.Error(w, "Failed to retrieve regressions", http.StatusInternalServerError)
+		return
+	}
+	if regressions == nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(regressions)
+	if err != nil {
+		http.Error(w, "Failed to encode regressions", http.StatusInternalServerError)
+	}
+}
+
+// parseRegressionQuery reads the regression filters from the query string, keys are case-insensitive.
+// The thresholds can only narrow down the regressions, which were detected with the configured ones.
+func parseRegressionQuery(values url.Values) (storage.RegressionQuery, error) {
+	var query storage.RegressionQuery
+	for key, keyValues := range values {
+		value := strings.TrimSpace(keyValues[0])
+		switch strings.ToLower(key) {
+		case "component":
+			component := strings.ToLower(value)
+			if !isOneOf(component, validComponents) {
+				return query, fmt.Errorf("invalid component: %s, must be one of %s", value, strings.Join(validComponents, ", "))
+			}
+			query.Component = component
+		case "group", "build", "vendor":
+			query.Group = strings.ToLower(value)
+		case "minreports":
+			minReports, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || minReports < 1 {
+				return query, fmt.Errorf("invalid minReports: %s, must be a positive number", value)
+			}
+			query.MinReports = minReports
+		case "mindrop":
+			minDrop, err := strconv.ParseFloat(value, 64)
+			if err != nil || minDrop <= 0 || minDrop > 1 {
+				return query, fmt.Errorf("invalid minDrop: %s, must be a number between 0 and 1", value)
+			}
+			query.MinDrop = minDrop
+		case "maxpvalue":
+			maxPValue, err := strconv.ParseFloat(value, 64)
+			if err != nil || maxPValue <= 0 || maxPValue > 1 {
+				return query, fmt.Errorf("invalid maxPValue: %s, must be a number between 0 and 1", value)
+			}
+			query.MaxPValue = maxPValue
+		}
+	}
+	return query, nil
+}
+
+// isOneOf tells if value is one of valid
+func isOneOf(value string, valid []string) bool {
+	for _, candidate := range valid {
+		if value == candidate {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package reports_controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/hardware"
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/proton"
+	"github.com/trsnaqe/protondb-api/pkg/storage"
+)
+
+var validRatings = []string{models.RatingBorked, models.RatingBronze, models.RatingSilver, models.RatingGold, models.RatingPlatinum, models.RatingNative}
+
+// setReportFilter sets the field of the filter named by a lowercased query key, keys that are not filters are ignored
+func setReportFilter(filter *storage.ReportFilter, key string, value string) error {
+	if comparison, ok := protonComparison(key, value); ok {
+		min, max, err := proton.ParseComparison(comparison)
+		if err != nil {
+			return err
+		}
+		if min > 0 {
+			filter.ProtonMin = min
+		}
+		if max > 0 {
+			filter.ProtonMax = max
+		}
+		return nil
+	}
+
+	switch key {
+	case "gpuvendor", "gpu_vendor":
+		filter.GPUVendor = strings.ToLower(value)
+	case "gpu":
+		filter.GPU = value
+	case "gpudriver", "gpu_driver":
+		filter.GPUDriver = value
+	case "os", "distro":
+		filter.OS = value
+	case "protonversion", "proton_version", "proton":
+		filter.ProtonVersion = value
+	case "kernelmin", "kernel_min":
+		version := hardware.VersionCode(value)
+		if version == 0 {
+			return fmt.Errorf("invalid kernel version: %s", value)
+		}
+		filter.KernelMin = version
+	case "kernelmax", "kernel_max":
+		version := hardware.VersionCode(value)
+		if version == 0 {
+			return fmt.Errorf("invalid kernel version: %s", value)
+		}
+		// Without a patch version, include every patch of the minor version
+		if strings.Count(value, ".") == 1 {
+			version += 999
+		}
+		filter.KernelMax = version
+	case "gpufamily", "gpu_family":
+		filter.GPUFamily = value
+	case "gpumodel", "gpu_model":
+		filter.GPUModel = value
+	case "drivervendor", "driver_vendor":
+		filter.DriverVendor = strings.ToLower(value)
+	case "drivermin", "driver_min":
+		version, err := driverVersionBound(value, false)
+		if err != nil {
+			return err
+		}
+		filter.DriverMin = version
+	case "drivermax", "driver_max":
+		version, err := driverVersionBound(value, true)
+		if err != nil {
+			return err
+		}
+		filter.DriverMax = version
+	case "distroname", "distro_name":
+		filter.DistroName = strings.ToLower(value)
+	case "distroversion", "distro_version":
+		return filter.SetDistroVersion(value)
+	case "cpuvendor", "cpu_vendor":
+		filter.CPUVendor = strings.ToLower(value)
+	case "cpumodel", "cpu_model", "cpu":
+		filter.CPUModel = value
+	case "rammin", "ram_min":
+		ram, err := strconv.Atoi(value)
+		if err != nil || ram < 1 {
+			return fmt.Errorf("invalid RAM: %s, must be a number of GB", value)
+		}
+		filter.RAMMin = ram
+	case "rammax", "ram_max":
+		ram, err := strconv.Atoi(value)
+		if err != nil || ram < 1 {
+			return fmt.Errorf("invalid RAM: %s, must be a number of GB", value)
+		}
+		filter.RAMMax = ram
+	case "protonmin", "proton_min":
+		min, _, err := proton.ParseComparison(">=" + value)
+		if err != nil {
+			return err
+		}
+		filter.ProtonMin = min
+	case "protonmax", "proton_max":
+		_, max, err := proton.ParseComparison("<=" + value)
+		if err != nil {
+			return err
+		}
+		filter.ProtonMax = max
+	case "protonbuild", "proton_build":
+		for _, build := range strings.Split(strings.ToLower(value), ",") {
+			build = strings.TrimSpace(build)
+			if !isOneOf(build, proton.Builds) {
+				return fmt.Errorf("invalid Proton build: %s, must be one of %s", build, strings.Join(proton.Builds, ", "))
+			}
+			filter.ProtonBuilds = append(filter.ProtonBuilds, build)
+		}
+	case "rating":
+		for _, rating := range strings.Split(strings.ToLower(value), ",") {
+			rating = strings.TrimSpace(rating)
+			if !isOneOf(rating, validRatings) {
+				return fmt.Errorf("invalid rating: %s, must be one of %s", rating, strings.Join(validRatings, ", "))
+			}
+			filter.Ratings = append(filter.Ratings, rating)
+		}
+	case "verdict":
+		filter.Verdict = strings.ToLower(value)
+	case "from":
+		from, err := parseTime(value, false)
+		if err != nil {
+			return err
+		}
+		filter.From = from
+	case "to":
+		to, err := parseTime(value, true)
+		if err != nil {
+			return err
+		}
+		filter.To = to
+	}
+	return nil
+}
+
+// driverVersionBound reads a driver version bound, drivers are often referred to by their major version
+// alone, e.g. 535. An upper bound covers every release of the versions left out.
+func driverVersionBound(value string, upper bool) (int64, error) {
+	if major, err := strconv.ParseInt(value, 10, 64); err == nil && major > 0 && major <= 999999 {
+		if upper {
+			return major*1000000 + 999999, nil
+		}
+		return major * 1000000, nil
+	}
+
+	version := hardware.VersionCode(value)
+	if version == 0 {
+		return 0, fmt.Errorf("invalid driver version: %s", value)
+	}
+	if upper && strings.Count(value, ".") == 1 {
+		version += 999
+	}
+	return version, nil
+}
+
+func isOneOf(value string, valid []string) bool {
+	for _, candidate := range valid {
+		if value == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// protonComparison reads a Proton version comparison written as a query parameter, e.g.
+// protonVersion>=8.0, which the query string splits into the key "protonversion>" and the value "8.0",
+// or protonVersion>8.0, which is a key without a value. protonVersion=8.0 stays a text filter.
+func protonComparison(key string, value string) (string, bool) {
+	for _, prefix := range []string{"protonversion", "proton_version", "proton"} {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		operator := key[len(prefix):]
+		if operator == "" {
+			// protonVersion==8.0 splits into protonversion and =8.0
+			if strings.HasPrefix(value, "=") {
+				return "=" + value, true
+			}
+			return "", false
+		}
+		if operator[0] != '<' && operator[0] != '>' {
+			return "", false
+		}
+		if value == "" {
+			return operator, true
+		}
+		return operator + "=" + value, true
+	}
+	return "", false
+}
+
+// parseTime reads a unix timestamp in seconds, an RFC 3339 time or a date. A date used as an upper
+// bound covers the whole day.
+func parseTime(value string, endOfDay bool) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed.UTC(), nil
+	}
+	if parsed, err := time.Parse("2006-01-02", value); err == nil {
+		if endOfDay {
+			parsed = parsed.Add(24*time.Hour - time.Nanosecond)
+		}
+		return parsed, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s, use a unix timestamp, an RFC 3339 time or a YYYY-MM-DD date", value)
+}
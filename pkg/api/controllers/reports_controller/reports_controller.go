@@ -2,6 +2,7 @@ package reports_controller
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -9,70 +10,31 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/trsnaqe/protondb-api/pkg/constants"
-	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/pagination"
 	"github.com/trsnaqe/protondb-api/pkg/services/reports_service"
 	"github.com/trsnaqe/protondb-api/pkg/storage"
 )
 
-// Endpoint to retrieve reports.
+// Endpoint to retrieve all reports, a page at a time.
 func GetReportsHandler(w http.ResponseWriter, r *http.Request) {
-
-	//replace with function below to activate GetStreamOfReports
-	//GetStreamOfReports(w, r)
-
-	message := "Status Code 503: Service Unavailable.\n\n" +
-		"This endpoint is currently unavailable as the server cannot handle the request. Please try again later or consider supporting the project by buying me a coffee. Your support helps keep this service running.\n\n" +
-		"Support the project by buying me a coffee at: https://www.buymeacoffee.com/trsnaqe"
-
-	w.WriteHeader(http.StatusServiceUnavailable)
-	w.Write([]byte(message))
-}
-
-func GetStreamOfReports(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	cursor, err := storage.GetAllReports()
+	page, err := pagination.ParseParams(r.URL.Query())
 	if err != nil {
-		http.Error(w, "Failed to retrieve reports", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer cursor.Close(r.Context())
 
-	encoder := json.NewEncoder(w)
-	w.Write([]byte("["))
+	versioned := isTrue(r.URL.Query().Get("versioned"))
+	raw := isTrue(r.URL.Query().Get("raw"))
 
-	first := true
-	var report models.Report
-	for cursor.Next(r.Context()) {
-		if err := cursor.Decode(&report); err != nil {
-			http.Error(w, "Failed to decode report", http.StatusInternalServerError)
-			return
-		}
-
-		if !first {
-			w.Write([]byte(","))
-		}
-		first = false
-
-		versioned := r.URL.Query().Get("versioned")
-		if versioned == "true" || versioned == "1" {
-			if err := encoder.Encode(report); err != nil {
-				http.Error(w, "Failed to encode report", http.StatusInternalServerError)
-				return
-			}
-		} else {
-			if err := encoder.Encode(report.Data); err != nil {
-				http.Error(w, "Failed to encode report data", http.StatusInternalServerError)
-				return
-			}
-		}
+	reports, next, err := reports_service.GetReports(reports_service.ReportOptions{Versioned: versioned, Raw: raw}, page)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve reports: %v", err), http.StatusInternalServerError)
+		return
 	}
 
-	w.Write([]byte("]"))
-
-	if f, ok := w.(http.Flusher); ok {
-		f.Flush()
-	}
+	writeReports(w, r, reports, next)
 }
 
 // v1 implementation, so no version filtering support
@@ -82,7 +44,16 @@ func GetReportsByGameIDHandler(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	gameID := params["gameId"]
 
-	reports, err := storage.GetReportsByGameID(gameID, "")
+	page, err := pagination.ParseParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	versioned := isTrue(r.URL.Query().Get("versioned"))
+	raw := isTrue(r.URL.Query().Get("raw"))
+
+	reports, next, err := reports_service.GetReportsByGameID(gameID, reports_service.ReportOptions{Versioned: versioned, Raw: raw}, page)
 	if err != nil {
 		if err.Error() == "game not found" {
 			http.Error(w, "Game not found", http.StatusNotFound)
@@ -91,33 +62,46 @@ func GetReportsByGameIDHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Failed to retrieve reports: %v", err), http.StatusInternalServerError)
 		return
 	}
-	if len(reports) == 0 {
+	if len(reports) == 0 && page.Cursor == nil {
 		http.Error(w, "No reports found for the game", http.StatusNotFound)
 		return
 	}
 
-	versioned := strings.ToLower(r.URL.Query().Get("versioned"))
-	if versioned == "true" || versioned == "1" {
-		json.NewEncoder(w).Encode(reports)
-	} else {
-		var data []interface{}
-		for _, report := range reports {
-			data = append(data, report.Data)
-		}
-		json.NewEncoder(w).Encode(data)
+	writeReports(w, r, reports, next)
+}
+
+// writeReports encodes a page of reports and links the next page
+func writeReports(w http.ResponseWriter, r *http.Request, reports []interface{}, next string) {
+	pagination.SetNext(w, r, next)
+
+	err := json.NewEncoder(w).Encode(reports)
+	if err != nil {
+		http.Error(w, "Failed to encode reports", http.StatusInternalServerError)
 	}
 }
 
-// Endpoint to retrieve reports by gameId.
+func isTrue(value string) bool {
+	value = strings.ToLower(value)
+	return value == "true" || value == "1"
+}
+
+// Endpoint to retrieve reports by query, a page at a time.
 func GetReportsByQueryHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var reports []interface{}
-	var err error
+	var next string
+
+	page, err := pagination.ParseParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	var appId, version, title string
-	var versioned bool
+	var appId, title string
+	var opts reports_service.ReportOptions
 	precision := constants.DEFAULT_SEARCH_PRECISION
+	reportsPerGame := defaultReportsPerGame
 
 	queryParams := r.URL.Query()
 	for key, values := range queryParams {
@@ -127,14 +111,35 @@ func GetReportsByQueryHandler(w http.ResponseWriter, r *http.Request) {
 			appId = strings.ToLower(values[0])
 		case "title":
 			title = strings.ToLower(values[0])
+		case "reports":
+			parsedReports, err := strconv.Atoi(values[0])
+			if err != nil || parsedReports < 1 || parsedReports > pagination.MaxLimit {
+				http.Error(w, fmt.Sprintf("Reports value must be a number between 1 and %d", pagination.MaxLimit), http.StatusBadRequest)
+				return
+			}
+			reportsPerGame = parsedReports
 		case "versioned":
-			versioned = values[0] == "true" || values[0] == "1"
+			opts.Versioned = values[0] == "true" || values[0] == "1"
+		case "raw":
+			opts.Raw = values[0] == "true" || values[0] == "1"
 		case "version":
 			switch values[0] {
 			case "1":
-				version = "V1"
+				opts.Version = "V1"
 			case "2":
-				version = "V2"
+				opts.Version = "V2"
+			}
+		case "sort":
+			opts.Sort, err = storage.ParseReportSort(values[0])
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		case "fields":
+			opts.Fields, err = reports_service.ParseFields(values[0])
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
 			}
 		case "precision":
 			parsedPrecision, err := strconv.ParseFloat(values[0], 32)
@@ -147,49 +152,83 @@ func GetReportsByQueryHandler(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			precision = float64(parsedPrecision)
+		default:
+			if err := setReportFilter(&opts.Filter, lowerKey, values[0]); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 		}
 	}
+	if err := opts.CheckFields(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if appId == "" && title != "" {
+		writeGamesWithReports(w, r, title, precision, reportsPerGame, opts, page)
+		return
+	}
+	if err := page.CheckSort(opts.Sort.String()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	if appId != "" {
-		reports, err = reports_service.GetReportsByGameID(appId, versioned, version)
-		if err != nil {
-			if err.Error() == "game not found" {
-				http.Error(w, "Game not found", http.StatusNotFound)
-				return
-			}
+		reports, next, err = reports_service.GetReportsByGameID(appId, opts, page)
+	} else {
+		reports, next, err = reports_service.GetReports(opts, page)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, pagination.ErrInvalidCursor):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case err.Error() == "game not found":
+			http.Error(w, "Game not found", http.StatusNotFound)
+		default:
 			http.Error(w, fmt.Sprintf("Failed to retrieve reports: %v", err), http.StatusInternalServerError)
-			return
-		}
-	} else if title != "" {
-		if len(title) < 5 {
-			http.Error(w, "Title query must be at least 5 characters long ", http.StatusBadRequest)
-			return
 		}
-		reports, err = reports_service.GetReportsByTitleSearch(title, versioned, version, precision)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to retrieve reports: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if len(reports) == 0 && page.Cursor == nil {
+		http.Error(w, "No reports found matching the query", http.StatusNotFound)
+		return
+	}
+
+	writeReports(w, r, reports, next)
+}
+
+// defaultReportsPerGame caps the reports of each game matched by a title search when no reports value is given
+const defaultReportsPerGame = 10
+
+// writeGamesWithReports writes a page of the games matching the title, each with some of its reports, the same way
+// /api/v2/games?title=&reports= does. Matches are ordered by score, so the reports cannot be sorted.
+func writeGamesWithReports(w http.ResponseWriter, r *http.Request, title string, precision float64, reportsPerGame int, opts reports_service.ReportOptions, page pagination.Params) {
+	if opts.Sort.Field != "" {
+		http.Error(w, "sort is not supported with title, matched games are ordered by score", http.StatusBadRequest)
+		return
+	}
+	if err := page.CheckSort("score"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	games, next, err := reports_service.GetGamesWithReportsByTitle(title, precision, reportsPerGame, opts, page)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-	} else {
-		/*
-			to activate de-comment this function and remove the code below
-				GetStreamOfReports(w, r)
-		*/
-		message := "Status Code 503: Service Unavailable.\n\n" +
-			"This endpoint is currently unavailable as the server cannot handle the request. Please try again later or consider supporting the project by buying me a coffee. Your support helps keep this service running.\n\n" +
-			"Support the project by buying me a coffee at: https://www.buymeacoffee.com/trsnaqe"
-
-		w.WriteHeader(http.StatusServiceUnavailable)
-		w.Write([]byte(message))
+		http.Error(w, fmt.Sprintf("Failed to retrieve reports: %v", err), http.StatusInternalServerError)
 		return
 	}
-
-	if len(reports) == 0 {
+	if len(games) == 0 && page.Cursor == nil {
 		http.Error(w, "No reports found matching the query", http.StatusNotFound)
 		return
 	}
 
-	encoder := json.NewEncoder(w)
-	err = encoder.Encode(reports)
+	pagination.SetNext(w, r, next)
+
+	err = json.NewEncoder(w).Encode(games)
 	if err != nil {
 		http.Error(w, "Failed to encode reports", http.StatusInternalServerError)
 	}
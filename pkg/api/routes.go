@@ -4,6 +4,7 @@ import (
 	"github.com/gorilla/mux"
 	gamesCtrl "github.com/trsnaqe/protondb-api/pkg/api/controllers/games_controller"
 	infoCtrl "github.com/trsnaqe/protondb-api/pkg/api/controllers/info_controller"
+	regressionsCtrl "github.com/trsnaqe/protondb-api/pkg/api/controllers/regressions_controller"
 	reportsCtrl "github.com/trsnaqe/protondb-api/pkg/api/controllers/reports_controller"
 	statsCtrl "github.com/trsnaqe/protondb-api/pkg/api/controllers/stats_controller"
 )
@@ -19,5 +20,12 @@ func SetupRoutes(r *mux.Router) {
 	r.HandleFunc("/api/stats", statsCtrl.StatsHandler).Methods("GET")
 
 	r.HandleFunc("/api/v2/games", gamesCtrl.GetGameByQueryHandler).Methods("GET")
+	r.HandleFunc("/api/v2/games/suggest", gamesCtrl.SuggestGamesHandler).Methods("GET")
+	r.HandleFunc("/api/v2/games/{appId}/stats", gamesCtrl.GetGameStatsHandler).Methods("GET")
+	r.HandleFunc("/api/v2/games/{appId}/match", gamesCtrl.MatchGameSetupHandler).Methods("GET")
+	r.HandleFunc("/api/v2/games/{appId}/proton", gamesCtrl.GetGameProtonHandler).Methods("GET")
+	r.HandleFunc("/api/v2/games/{appId}/timeline", gamesCtrl.GetGameTimelineHandler).Methods("GET")
+	r.HandleFunc("/api/v2/games/{appId}/regressions", regressionsCtrl.GetGameRegressionsHandler).Methods("GET")
+	r.HandleFunc("/api/v2/regressions", regressionsCtrl.GetRegressionsHandler).Methods("GET")
 	r.HandleFunc("/api/v2/reports", reportsCtrl.GetReportsByQueryHandler).Methods("GET")
 }
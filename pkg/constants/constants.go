@@ -1,3 +1,3 @@
 package constants
 
-var DEFAULT_SEARCH_PRECISION float64 = 1
+var DEFAULT_SEARCH_PRECISION float64 = 0.75
@@ -0,0 +1,203 @@
+package hardware
+
+import (
+	"regexp"
+	"strings"
+)
+
+// GPU is a parsed GPU name. Family is the series the model belongs to, e.g. "GeForce RTX 30" for an
+// "RTX 3080 Ti", or the chip when only the chip is named, e.g. "Navi 1x" for "AMD RADV NAVI10".
+type GPU struct {
+	Vendor string `bson:"vendor" json:"vendor"`
+	Family string `bson:"family,omitempty" json:"family,omitempty"`
+	Model  string `bson:"model,omitempty" json:"model,omitempty"`
+}
+
+// gpuVendorPatterns are tried in order against the GPU string of a report. Reports name the GPU
+// the way the driver or the system info tool does, e.g. "NVIDIA GeForce GTX 1060 6GB",
+// "AMD RADV NAVI10", "Mesa DRI Intel(R) UHD Graphics 620".
+var gpuVendorPatterns = []struct {
+	vendor  string
+	pattern *regexp.Regexp
+}{
+	{VendorNvidia, regexp.MustCompile(`(?i)nvidia|geforce|quadro|\bgtx?\b|\brtx\b|\bnv[0-9a-f]{2,3}\b|nouveau`)},
+	{VendorAMD, regexp.MustCompile(`(?i)\bamd\b|\bati\b|radeon|radv|\brx ?[0-9]{3,4}|vega|navi|polaris|amdgpu|\bgfx[0-9]+`)},
+	{VendorIntel, regexp.MustCompile(`(?i)intel|\b[hu]?hd graphics|iris|\banv\b`)},
+}
+
+// GPUVendor tells the vendor of a GPU from its name, VendorUnknown if none matches
+func GPUVendor(gpu string) string {
+	for _, candidate := range gpuVendorPatterns {
+		if candidate.pattern.MatchString(gpu) {
+			return candidate.vendor
+		}
+	}
+	return VendorUnknown
+}
+
+var (
+	nvidiaModelPattern = regexp.MustCompile(`(?i)\b(rtx|gtx|gts|gt|mx)\s*(\d{3,4})\b(?:\s*(ti|super))?`)
+	nvidiaTitanPattern = regexp.MustCompile(`(?i)\btitan\s*(rtx|v|xp|x|z|black)?\b`)
+	quadroPattern      = regexp.MustCompile(`(?i)\bquadro\s+([a-z]*\s*\d+[a-z]*)`)
+
+	amdModelPattern = regexp.MustCompile(`(?i)\b(rx|r[579]|hd)\s*(\d{3,4})m?\b(?:\s*(xtx|xt|x))?`)
+	amdVegaPattern  = regexp.MustCompile(`(?i)\b(?:rx\s*)?vega\s*(\d+)\b`)
+	// amdChips are the chips RADV and the open drivers name the GPU by, with the family they belong to
+	amdChips = []struct {
+		pattern *regexp.Regexp
+		family  string
+	}{
+		{regexp.MustCompile(`(?i)\bnavi\s*(3\d)\b`), "Navi 3x"},
+		{regexp.MustCompile(`(?i)\bnavi\s*(2\d)\b|sienna|navy_flounder|dimgrey|beige_goby`), "Navi 2x"},
+		{regexp.MustCompile(`(?i)\bnavi\s*(1\d)\b`), "Navi 1x"},
+		{regexp.MustCompile(`(?i)vangogh|van gogh|custom gpu 0405`), "Van Gogh"},
+		{regexp.MustCompile(`(?i)rembrandt`), "Rembrandt"},
+		{regexp.MustCompile(`(?i)phoenix`), "Phoenix"},
+		{regexp.MustCompile(`(?i)renoir|cezanne|lucienne|barcelo`), "Renoir"},
+		{regexp.MustCompile(`(?i)raven|picasso`), "Raven"},
+		{regexp.MustCompile(`(?i)polaris\s*\d*`), "Polaris"},
+		{regexp.MustCompile(`(?i)\bvega\s*(10|20|12)\b`), "Vega"},
+	}
+
+	intelArcPattern    = regexp.MustCompile(`(?i)\barc\s*(?:\(tm\)\s*)?([ab]\d{3}m?)\b`)
+	intelModelPattern  = regexp.MustCompile(`(?i)\b(iris\s*(?:\(r\)\s*)?(?:plus|pro)?|u?hd)\s*graphics(?:\s*(p?\d{3,4}))?`)
+	intelIrisXePattern = regexp.MustCompile(`(?i)\biris\s*(?:\(r\)\s*)?xe\b`)
+)
+
+// ParseGPU parses a GPU name into its vendor, family and model
+func ParseGPU(gpu string) GPU {
+	parsed := GPU{Vendor: GPUVendor(gpu)}
+	switch parsed.Vendor {
+	case VendorNvidia:
+		parsed.Family, parsed.Model = nvidiaModel(gpu)
+	case VendorAMD:
+		parsed.Family, parsed.Model = amdModel(gpu)
+	case VendorIntel:
+		parsed.Family, parsed.Model = intelModel(gpu)
+	}
+	return parsed
+}
+
+func nvidiaModel(gpu string) (string, string) {
+	if match := nvidiaModelPattern.FindStringSubmatch(gpu); match != nil {
+		prefix, number := strings.ToUpper(match[1]), match[2]
+		model := prefix + " " + number + suffix(match[3])
+		// 4 digit models are named by generation and tier, 1080 is in the 10 series, 3 digit ones by the
+		// hundreds, 970 is in the 900 series
+		series := number[:1] + "00"
+		if len(number) == 4 {
+			series = number[:2]
+		}
+		return "GeForce " + prefix + " " + series, model
+	}
+	if match := nvidiaTitanPattern.FindStringSubmatch(gpu); match != nil {
+		return "Titan", strings.TrimSpace("TITAN " + strings.ToUpper(match[1]))
+	}
+	if match := quadroPattern.FindStringSubmatch(gpu); match != nil {
+		return "Quadro", "Quadro " + strings.ToUpper(strings.Join(strings.Fields(match[1]), " "))
+	}
+	return "", ""
+}
+
+func amdModel(gpu string) (string, string) {
+	if match := amdModelPattern.FindStringSubmatch(gpu); match != nil {
+		prefix, number := strings.ToUpper(match[1]), match[2]
+		model := prefix + " " + number + suffix(match[3])
+		// RX 6800 is in the 6000 series, RX 580 in the 500 series
+		series := number[:1] + strings.Repeat("0", len(number)-1)
+		return "Radeon " + prefix + " " + series, model
+	}
+	if match := amdVegaPattern.FindStringSubmatch(gpu); match != nil && match[1] != "10" && match[1] != "20" && match[1] != "12" {
+		return "Radeon Vega", "Vega " + match[1]
+	}
+	for _, chip := range amdChips {
+		if chip.pattern.MatchString(gpu) {
+			return chip.family, ""
+		}
+	}
+	return "", ""
+}
+
+func intelModel(gpu string) (string, string) {
+	if match := intelArcPattern.FindStringSubmatch(gpu); match != nil {
+		return "Arc", "Arc " + strings.ToUpper(match[1])
+	}
+	if intelIrisXePattern.MatchString(gpu) {
+		return "Iris Xe", "Iris Xe Graphics"
+	}
+	if match := intelModelPattern.FindStringSubmatch(gpu); match != nil {
+		line := strings.Join(strings.Fields(strings.ReplaceAll(strings.ToLower(match[1]), "(r)", "")), " ")
+		family := intelLines[line] + " Graphics"
+		if match[2] == "" {
+			return family, ""
+		}
+		return family, family + " " + strings.ToUpper(match[2])
+	}
+	return "", ""
+}
+
+// intelLines are the product lines of Intel integrated GPUs, as matched by intelModelPattern
+var intelLines = map[string]string{
+	"hd":        "HD",
+	"uhd":       "UHD",
+	"iris":      "Iris",
+	"iris plus": "Iris Plus",
+	"iris pro":  "Iris Pro",
+}
+
+// suffix formats the variant of a model, e.g. " Ti" or " XT"
+func suffix(variant string) string {
+	switch strings.ToLower(variant) {
+	case "":
+		return ""
+	case "ti":
+		return " Ti"
+	case "super":
+		return " Super"
+	}
+	return " " + strings.ToUpper(variant)
+}
+
+// Driver vendor IDs. Mesa covers the open drivers of every GPU vendor, RADV, RadeonSI, ANV, Iris and nouveau.
+const (
+	DriverNvidia  = "nvidia"
+	DriverMesa    = "mesa"
+	DriverAMDVLK  = "amdvlk"
+	DriverUnknown = "unknown"
+)
+
+// Driver is a parsed GPU driver
+type Driver struct {
+	Vendor  string   `bson:"vendor" json:"vendor"`
+	Version *Version `bson:"version,omitempty" json:"version,omitempty"`
+}
+
+var driverVendorPatterns = []struct {
+	vendor  string
+	pattern *regexp.Regexp
+}{
+	{DriverMesa, regexp.MustCompile(`(?i)mesa|radv|radeonsi|\banv\b|nouveau|llvmpipe`)},
+	{DriverAMDVLK, regexp.MustCompile(`(?i)amdvlk|amdgpu-pro|amdgpu pro|\bpro\b`)},
+	{DriverNvidia, regexp.MustCompile(`(?i)nvidia`)},
+}
+
+// ParseDriver parses a driver string such as "NVIDIA 535.54.03", "4.6.0 NVIDIA 470.57.02" or
+// "Mesa 23.1.3 (LLVM 15)". The version is the first one following the vendor name, so the OpenGL
+// version some strings start with is skipped.
+func ParseDriver(driver string) Driver {
+	parsed := Driver{Vendor: DriverUnknown}
+	if strings.TrimSpace(driver) == "" {
+		return parsed
+	}
+
+	rest := driver
+	for _, candidate := range driverVendorPatterns {
+		if location := candidate.pattern.FindStringIndex(driver); location != nil {
+			parsed.Vendor = candidate.vendor
+			rest = driver[location[1]:]
+			break
+		}
+	}
+	parsed.Version = ParseVersion(rest)
+	return parsed
+}
@@ -0,0 +1,59 @@
+package hardware
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGPU(t *testing.T) {
+	tests := []struct {
+		gpu  string
+		want GPU
+	}{
+		{"", GPU{Vendor: VendorUnknown}},
+		{"llvmpipe (LLVM 15.0.7, 256 bits)", GPU{Vendor: VendorUnknown}},
+		{"NVIDIA GeForce GTX 1060 6GB", GPU{VendorNvidia, "GeForce GTX 10", "GTX 1060"}},
+		{"NVIDIA GeForce RTX 3080 Ti", GPU{VendorNvidia, "GeForce RTX 30", "RTX 3080 Ti"}},
+		{"NVIDIA GeForce GTX 1070/PCIe/SSE2", GPU{VendorNvidia, "GeForce GTX 10", "GTX 1070"}},
+		{"NVIDIA GeForce GTX 970", GPU{VendorNvidia, "GeForce GTX 900", "GTX 970"}},
+		{"NVIDIA GeForce RTX 2070 SUPER", GPU{VendorNvidia, "GeForce RTX 20", "RTX 2070 Super"}},
+		{"NVIDIA TITAN Xp", GPU{VendorNvidia, "Titan", "TITAN XP"}},
+		{"Quadro P2000", GPU{VendorNvidia, "Quadro", "Quadro P2000"}},
+		{"AMD RADV NAVI10", GPU{Vendor: VendorAMD, Family: "Navi 1x"}},
+		{"AMD RADV NAVI21", GPU{Vendor: VendorAMD, Family: "Navi 2x"}},
+		{"AMD Custom GPU 0405 (RADV VANGOGH)", GPU{Vendor: VendorAMD, Family: "Van Gogh"}},
+		{"AMD Radeon RX 6800 XT", GPU{VendorAMD, "Radeon RX 6000", "RX 6800 XT"}},
+		{"AMD Radeon RX 580 Series (POLARIS10, DRM 3.42.0)", GPU{VendorAMD, "Radeon RX 500", "RX 580"}},
+		{"AMD Radeon RX Vega 56", GPU{VendorAMD, "Radeon Vega", "Vega 56"}},
+		{"AMD RADV VEGA10", GPU{Vendor: VendorAMD, Family: "Vega"}},
+		{"Mesa DRI Intel(R) UHD Graphics 620", GPU{VendorIntel, "UHD Graphics", "UHD Graphics 620"}},
+		{"Intel(R) HD Graphics", GPU{Vendor: VendorIntel, Family: "HD Graphics"}},
+		{"Intel(R) Iris(R) Xe Graphics", GPU{VendorIntel, "Iris Xe", "Iris Xe Graphics"}},
+		{"Intel(R) Arc(tm) A770 Graphics (DG2)", GPU{VendorIntel, "Arc", "Arc A770"}},
+	}
+	for _, test := range tests {
+		if got := ParseGPU(test.gpu); got != test.want {
+			t.Errorf("ParseGPU(%q) = %+v, want %+v", test.gpu, got, test.want)
+		}
+	}
+}
+
+func TestParseDriver(t *testing.T) {
+	tests := []struct {
+		driver string
+		want   Driver
+	}{
+		{"", Driver{Vendor: DriverUnknown}},
+		{"NVIDIA 535.54.03", Driver{DriverNvidia, &Version{535, 54, 3, 535054003}}},
+		{"4.6.0 NVIDIA 470.57.02", Driver{DriverNvidia, &Version{470, 57, 2, 470057002}}},
+		{"Mesa 23.1.3 (LLVM 15)", Driver{DriverMesa, &Version{23, 1, 3, 23001003}}},
+		{"4.6 (Compatibility Profile) Mesa 22.0.1", Driver{DriverMesa, &Version{22, 0, 1, 22000001}}},
+		{"amdgpu-pro 22.40", Driver{DriverAMDVLK, &Version{22, 40, 0, 22040000}}},
+		{"2023.2", Driver{DriverUnknown, &Version{2023, 2, 0, 2023002000}}},
+	}
+	for _, test := range tests {
+		if got := ParseDriver(test.driver); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseDriver(%q) = %+v, want %+v", test.driver, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Package hardware parses the free-form system info of reports into structured fields.
+//
+// Reports name their hardware the way the tool that collected it did, e.g. "NVIDIA GeForce GTX 1070/PCIe/SSE2",
+// "Mesa 23.1.3 (LLVM 15)" or "Intel(R) Core(TM) i5-4590 CPU @ 3.30GHz". Vendors and distros are identified
+// by lowercase IDs, families and models are named the way vendors market them.
+package hardware
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Vendor IDs, GPUs and CPUs that cannot be told apart get VendorUnknown
+const (
+	VendorNvidia  = "nvidia"
+	VendorAMD     = "amd"
+	VendorIntel   = "intel"
+	VendorUnknown = "unknown"
+)
+
+// Info is the structured form of the system info of a report. Fields that cannot be parsed are left empty.
+type Info struct {
+	GPU    GPU      `bson:"gpu" json:"gpu"`
+	Driver Driver   `bson:"driver" json:"driver"`
+	Kernel *Version `bson:"kernel,omitempty" json:"kernel,omitempty"`
+	Distro Distro   `bson:"distro" json:"distro"`
+	CPU    CPU      `bson:"cpu" json:"cpu"`
+	// RAMGB is the memory rounded to the nearest GB
+	RAMGB int `bson:"ramGb,omitempty" json:"ramGb,omitempty"`
+}
+
+// Parse parses the system info fields of a report
+func Parse(gpu, driver, kernel, os, cpu, ram string) Info {
+	return Info{
+		GPU:    ParseGPU(gpu),
+		Driver: ParseDriver(driver),
+		Kernel: ParseKernel(kernel),
+		Distro: ParseDistro(os),
+		CPU:    ParseCPU(cpu),
+		RAMGB:  ParseRAM(ram),
+	}
+}
+
+// Version is a major.minor.patch version, along with a number ordering like it
+type Version struct {
+	Major int `bson:"major"`
+	Minor int `bson:"minor"`
+	Patch int `bson:"patch"`
+	// Code is major*1000000 + minor*1000 + patch, for range queries
+	Code int64 `bson:"code"`
+}
+
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseVersion reads the first major.minor or major.minor.patch version found in a string.
+// It returns nil if there is none, or if a part is too large to be encoded in the version code.
+func ParseVersion(value string) *Version {
+	match := versionPattern.FindStringSubmatch(value)
+	if match == nil {
+		return nil
+	}
+
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	var patch int
+	if match[3] != "" {
+		patch, _ = strconv.Atoi(match[3])
+	}
+	if minor > 999 || patch > 999 || major > 999999 {
+		return nil
+	}
+	return &Version{
+		Major: major,
+		Minor: minor,
+		Patch: patch,
+		Code:  int64(major)*1000000 + int64(minor)*1000 + int64(patch),
+	}
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// MarshalJSON serves versions as strings, e.g. "5.15.0"
+func (v Version) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
+
+// VersionCode returns the code of the first version found in a string, e.g. 5004000 for "5.4.0-42-generic",
+// 0 if there is none
+func VersionCode(value string) int64 {
+	version := ParseVersion(value)
+	if version == nil {
+		return 0
+	}
+	return version.Code
+}
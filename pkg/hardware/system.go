@@ -0,0 +1,126 @@
+package hardware
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParseKernel reads the version of a kernel string, e.g. 5.4.0 for "5.4.0-42-generic"
+func ParseKernel(kernel string) *Version {
+	return ParseVersion(kernel)
+}
+
+// Distro is a parsed OS name. Name is a lowercase distro ID, Version is the release as written,
+// empty for rolling releases.
+type Distro struct {
+	Name    string `bson:"name,omitempty" json:"name,omitempty"`
+	Version string `bson:"version,omitempty" json:"version,omitempty"`
+}
+
+// distros are tried in order against the OS string of a report, derivatives before the distros they
+// mention, e.g. "Pop!_OS 22.04 LTS" and "Linux Mint 21.1 (Ubuntu Jammy)" before Ubuntu
+var distros = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"steamos", regexp.MustCompile(`(?i)steam\s*os|holo`)},
+	{"pop_os", regexp.MustCompile(`(?i)pop!?_?\s*os`)},
+	{"mint", regexp.MustCompile(`(?i)\bmint\b`)},
+	{"elementary", regexp.MustCompile(`(?i)elementary`)},
+	{"zorin", regexp.MustCompile(`(?i)zorin`)},
+	{"neon", regexp.MustCompile(`(?i)\bneon\b`)},
+	{"ubuntu", regexp.MustCompile(`(?i)[kxl]?ubuntu`)},
+	{"manjaro", regexp.MustCompile(`(?i)manjaro`)},
+	{"endeavouros", regexp.MustCompile(`(?i)endeavour`)},
+	{"garuda", regexp.MustCompile(`(?i)garuda`)},
+	{"arch", regexp.MustCompile(`(?i)\barch\b|arcolinux`)},
+	{"nobara", regexp.MustCompile(`(?i)nobara`)},
+	{"fedora", regexp.MustCompile(`(?i)fedora`)},
+	{"opensuse", regexp.MustCompile(`(?i)suse`)},
+	{"mx", regexp.MustCompile(`(?i)\bmx\b`)},
+	{"debian", regexp.MustCompile(`(?i)debian`)},
+	{"nixos", regexp.MustCompile(`(?i)nixos`)},
+	{"gentoo", regexp.MustCompile(`(?i)gentoo`)},
+	{"void", regexp.MustCompile(`(?i)\bvoid\b`)},
+	{"solus", regexp.MustCompile(`(?i)solus`)},
+	{"centos", regexp.MustCompile(`(?i)centos|rocky|alma|red hat|rhel`)},
+}
+
+var distroVersionPattern = regexp.MustCompile(`\b\d+(?:\.\d+)*\b`)
+
+// ParseDistro parses an OS string such as "Ubuntu 20.04.2 LTS" or "Arch Linux" into a distro ID and version
+func ParseDistro(os string) Distro {
+	for _, distro := range distros {
+		location := distro.pattern.FindStringIndex(os)
+		if location == nil {
+			continue
+		}
+		// The release follows the name, "Linux Mint 21.1 (Ubuntu Jammy)" is Mint 21.1
+		return Distro{Name: distro.name, Version: distroVersionPattern.FindString(os[location[1]:])}
+	}
+	return Distro{}
+}
+
+// CPU is a parsed CPU name. Model is the name without the vendor and the marketing noise,
+// e.g. "Ryzen 7 3700X" or "Core i5-4590".
+type CPU struct {
+	Vendor string `bson:"vendor" json:"vendor"`
+	Model  string `bson:"model,omitempty" json:"model,omitempty"`
+}
+
+var (
+	cpuVendorPatterns = []struct {
+		vendor  string
+		pattern *regexp.Regexp
+	}{
+		{VendorAMD, regexp.MustCompile(`(?i)\bamd\b|ryzen|threadripper|athlon|phenom|\bfx-?\d|epyc|custom apu`)},
+		{VendorIntel, regexp.MustCompile(`(?i)intel|\bcore\b|\bi[3579]-|xeon|pentium|celeron|atom`)},
+	}
+	// cpuNoise is what CPU names carry besides the model, e.g. "Intel(R) Core(TM) i5-4590 CPU @ 3.30GHz",
+	// "AMD Ryzen 7 3700X 8-Core Processor"
+	cpuNoise = regexp.MustCompile(`(?i)\((?:r|tm)\)|\b(?:amd|intel|cpu|processor|apu with radeon.*|with radeon.*)\b|\b\d+-core\b|@.*$|\b\d+(?:\.\d+)?\s*ghz\b`)
+)
+
+// ParseCPU parses a CPU name into its vendor and model
+func ParseCPU(cpu string) CPU {
+	parsed := CPU{Vendor: VendorUnknown}
+	for _, candidate := range cpuVendorPatterns {
+		if candidate.pattern.MatchString(cpu) {
+			parsed.Vendor = candidate.vendor
+			break
+		}
+	}
+	parsed.Model = strings.Join(strings.Fields(cpuNoise.ReplaceAllString(cpu, " ")), " ")
+	return parsed
+}
+
+var ramPattern = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)\s*(tib|tb|gib|gb|go|g|mib|mb|mo|m|kib|kb|k)?\b`)
+
+// ParseRAM reads an amount of memory such as "16 GB", "15.6 GiB" or "16384 MB" and returns it rounded
+// to the nearest GB, 0 if there is none. Numbers without a unit are taken as GB, or MB from 256 on.
+func ParseRAM(ram string) int {
+	match := ramPattern.FindStringSubmatch(ram)
+	if match == nil {
+		return 0
+	}
+
+	amount, err := strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+	if err != nil {
+		return 0
+	}
+	switch strings.ToLower(match[2]) {
+	case "tib", "tb":
+		amount *= 1024
+	case "mib", "mb", "mo", "m":
+		amount /= 1024
+	case "kib", "kb", "k":
+		amount /= 1024 * 1024
+	case "":
+		if amount >= 256 {
+			amount /= 1024
+		}
+	}
+	return int(math.Round(amount))
+}
@@ -0,0 +1,90 @@
+package hardware
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDistro(t *testing.T) {
+	tests := []struct {
+		os   string
+		want Distro
+	}{
+		{"", Distro{}},
+		{"Windows 10", Distro{}},
+		{"Ubuntu 20.04.2 LTS", Distro{"ubuntu", "20.04.2"}},
+		{"Kubuntu 22.10", Distro{"ubuntu", "22.10"}},
+		{"Linux Mint 21.1 (Ubuntu Jammy)", Distro{"mint", "21.1"}},
+		{"Pop!_OS 22.04 LTS", Distro{"pop_os", "22.04"}},
+		{"Arch Linux", Distro{Name: "arch"}},
+		{"Manjaro Linux", Distro{Name: "manjaro"}},
+		{"SteamOS Holo", Distro{Name: "steamos"}},
+		{"Fedora Linux 38 (Workstation Edition)", Distro{"fedora", "38"}},
+		{"openSUSE Tumbleweed", Distro{Name: "opensuse"}},
+		{"Debian GNU/Linux 12 (bookworm)", Distro{"debian", "12"}},
+	}
+	for _, test := range tests {
+		if got := ParseDistro(test.os); got != test.want {
+			t.Errorf("ParseDistro(%q) = %+v, want %+v", test.os, got, test.want)
+		}
+	}
+}
+
+func TestParseKernel(t *testing.T) {
+	tests := []struct {
+		kernel string
+		want   *Version
+	}{
+		{"", nil},
+		{"unknown", nil},
+		{"5.4.0-42-generic", &Version{5, 4, 0, 5004000}},
+		{"6.1.12-arch1-1", &Version{6, 1, 12, 6001012}},
+		{"6.4", &Version{6, 4, 0, 6004000}},
+		{"5.1000.0", nil},
+	}
+	for _, test := range tests {
+		if got := ParseKernel(test.kernel); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseKernel(%q) = %+v, want %+v", test.kernel, got, test.want)
+		}
+	}
+}
+
+func TestParseCPU(t *testing.T) {
+	tests := []struct {
+		cpu  string
+		want CPU
+	}{
+		{"", CPU{Vendor: VendorUnknown}},
+		{"AMD Ryzen 7 3700X 8-Core Processor", CPU{VendorAMD, "Ryzen 7 3700X"}},
+		{"Intel(R) Core(TM) i5-4590 CPU @ 3.30GHz", CPU{VendorIntel, "Core i5-4590"}},
+		{"AMD Custom APU 0405", CPU{VendorAMD, "Custom APU 0405"}},
+		{"AMD Ryzen 5 5600G with Radeon Graphics", CPU{VendorAMD, "Ryzen 5 5600G"}},
+	}
+	for _, test := range tests {
+		if got := ParseCPU(test.cpu); got != test.want {
+			t.Errorf("ParseCPU(%q) = %+v, want %+v", test.cpu, got, test.want)
+		}
+	}
+}
+
+func TestParseRAM(t *testing.T) {
+	tests := []struct {
+		ram  string
+		want int
+	}{
+		{"", 0},
+		{"unknown", 0},
+		{"16 GB", 16},
+		{"15.6 GiB", 16},
+		{"16384 MB", 16},
+		{"7,7 Go", 8},
+		{"32", 32},
+		{"8192", 8},
+		{"1 TB", 1024},
+	}
+	for _, test := range tests {
+		if got := ParseRAM(test.ram); got != test.want {
+			t.Errorf("ParseRAM(%q) = %d, want %d", test.ram, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// GameCount is the number of reports of a game
+type GameCount struct {
+	AppID string `bson:"_id" json:"appId"`
+	Title string `bson:"title" json:"title"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+// DatasetStats are analytics over all stored reports, precomputed after each ingestion.
+// Distributions are ordered by descending count, then value.
+type DatasetStats struct {
+	ComputedAt time.Time `bson:"computedAt" json:"computedAt"`
+	// ReportsPerMonth is ordered by month, formatted as YYYY-MM
+	ReportsPerMonth []StatCount `bson:"reportsPerMonth" json:"reportsPerMonth"`
+	TopGames        []GameCount `bson:"topGames" json:"topGames"`
+	// Tiers counts the games by the tier of their summary, Ratings the reports by their rating
+	Tiers             []StatCount `bson:"tiers" json:"tiers"`
+	Ratings           []StatCount `bson:"ratings" json:"ratings"`
+	TopGPUs           []StatCount `bson:"topGpus" json:"topGpus"`
+	TopDistros        []StatCount `bson:"topDistros" json:"topDistros"`
+	TopProtonVersions []StatCount `bson:"topProtonVersions" json:"topProtonVersions"`
+}
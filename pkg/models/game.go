@@ -3,9 +3,14 @@ package models
 import "go.mongodb.org/mongo-driver/bson/primitive"
 
 type Game struct {
-	ID      primitive.ObjectID   `bson:"_id,omitempty"`
-	AppID   string               `bson:"appId"`
-	Title   *string              `bson:"title"`
+	ID    primitive.ObjectID `bson:"_id,omitempty"`
+	AppID string             `bson:"appId"`
+	// Title is the canonical title, the most frequent of Titles
+	Title *string `bson:"title"`
+	// Titles are the titles the reports of the game were filed under
+	Titles []TitleAlias `bson:"titles,omitempty"`
+	// Deprecated: reports reference their game with their appId and gameId fields instead.
+	// The list is no longer updated and only kept for the data stored before.
 	Reports []primitive.ObjectID `bson:"reports"`
 }
 
@@ -16,3 +21,9 @@ func NewGame(appID string, title *string) *Game {
 		Reports: []primitive.ObjectID{},
 	}
 }
+
+// MatchedGame is a game found by a title search, with some of its reports
+type MatchedGame struct {
+	Game           `bson:",inline"`
+	MatchedReports []Report `bson:"matchedReports"`
+}
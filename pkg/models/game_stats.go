@@ -0,0 +1,22 @@
+package models
+
+// StatCount is the number of reports with a value of a field
+type StatCount struct {
+	Value string `bson:"_id" json:"value"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+// GameStats breaks the reports of a game down by month and by some of their normalized fields.
+// Distributions are ordered by descending count, then value; reports without the field are left out.
+type GameStats struct {
+	AppID string `json:"appId"`
+	Total int64  `json:"total"`
+	// ReportsPerMonth is ordered by month, formatted as YYYY-MM
+	ReportsPerMonth []StatCount `json:"reportsPerMonth"`
+	Ratings         []StatCount `json:"ratings"`
+	ProtonVersions  []StatCount `json:"protonVersions"`
+	GPUVendors      []StatCount `json:"gpuVendors"`
+	Distros         []StatCount `json:"distros"`
+	Tweaks          []StatCount `json:"tweaks"`
+	LaunchOptions   []StatCount `json:"launchOptions"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type GameSummary struct {
 	BestReportedTier string  `json:"bestReportedTier"`
 	Confidence       string  `json:"confidence"`
@@ -8,3 +10,9 @@ type GameSummary struct {
 	Total            int     `json:"total"`
 	TrendingTier     string  `json:"trendingTier"`
 }
+
+// ReportRating is the rating of a report along with when it was written, all a game summary needs
+type ReportRating struct {
+	Rating    string    `bson:"rating"`
+	Timestamp time.Time `bson:"timestamp"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// GameTimeline breaks the reports of a game down by period, from the period of its first report to the
+// period of its last one. Periods without reports are included, so the buckets chart on an even scale.
+type GameTimeline struct {
+	AppID string `json:"appId"`
+	// Bucket is the length of the periods: day, week, month, quarter or year
+	Bucket  string           `json:"bucket"`
+	Buckets []TimelineBucket `json:"buckets"`
+	// Undated is the number of reports without a timestamp, which are left out of the buckets
+	Undated int64 `json:"undated"`
+}
+
+// TimelineBucket counts the reports of a game written in a period and their ratings.
+// Score and Tier are computed like the summary of the game, without weighing reports by age.
+type TimelineBucket struct {
+	// Period is the label of the period: 2023-02-01, 2023-W05, 2023-02, 2023-Q1 or 2023
+	Period  string    `json:"period"`
+	Start   time.Time `json:"start"`
+	Reports int64     `json:"reports"`
+	// Ratings counts the reports of each rating, every rating is listed
+	Ratings map[string]int64 `json:"ratings"`
+	Score   float64          `json:"score"`
+	Tier    string           `json:"tier"`
+}
@@ -0,0 +1,316 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/hardware"
+	"github.com/trsnaqe/protondb-api/pkg/proton"
+)
+
+// NormalizedReport is the canonical shape of a report, produced from both V1 and V2 reports at ingest time
+type NormalizedReport struct {
+	AppID         string    `bson:"appId" json:"appId"`
+	Title         string    `bson:"title" json:"title"`
+	Timestamp     time.Time `bson:"timestamp" json:"timestamp"`
+	Rating        string    `bson:"rating" json:"rating"`
+	RatingRank    int       `bson:"ratingRank" json:"-"`
+	Verdict       string    `bson:"verdict,omitempty" json:"verdict,omitempty"`
+	ProtonVersion string    `bson:"protonVersion,omitempty" json:"protonVersion,omitempty"`
+	// Proton is the Proton version parsed into its build and a comparable version
+	Proton        proton.Version         `bson:"proton" json:"proton"`
+	OS            string                 `bson:"os,omitempty" json:"os,omitempty"`
+	Kernel        string                 `bson:"kernel,omitempty" json:"kernel,omitempty"`
+	KernelVersion int64                  `bson:"kernelVersion" json:"-"`
+	GPU           string                 `bson:"gpu,omitempty" json:"gpu,omitempty"`
+	GPUVendor     string                 `bson:"gpuVendor" json:"gpuVendor"`
+	GPUDriver     string                 `bson:"gpuDriver,omitempty" json:"gpuDriver,omitempty"`
+	CPU           string                 `bson:"cpu,omitempty" json:"cpu,omitempty"`
+	RAM           string                 `bson:"ram,omitempty" json:"ram,omitempty"`
+	Notes         string                 `bson:"notes,omitempty" json:"notes,omitempty"`
+	Tweaks        []string               `bson:"tweaks,omitempty" json:"tweaks,omitempty"`
+	LaunchOptions string                 `bson:"launchOptions,omitempty" json:"launchOptions,omitempty"`
+	Responses     map[string]interface{} `bson:"responses,omitempty" json:"responses,omitempty"`
+	// Hardware is the system info parsed into structured fields, GPUVendor and KernelVersion mirror it
+	Hardware hardware.Info `bson:"hardware" json:"hardware"`
+}
+
+// Ratings from worst to best, as used by ProtonDB
+const (
+	RatingBorked   = "borked"
+	RatingBronze   = "bronze"
+	RatingSilver   = "silver"
+	RatingGold     = "gold"
+	RatingPlatinum = "platinum"
+	RatingNative   = "native"
+)
+
+// RatingRank orders ratings from worst to best, unknown ratings rank 0
+func RatingRank(rating string) int {
+	for rank, known := range []string{RatingBorked, RatingBronze, RatingSilver, RatingGold, RatingPlatinum, RatingNative} {
+		if rating == known {
+			return rank + 1
+		}
+	}
+	return 0
+}
+
+// v2FaultKeys are the V2 questions answered with "yes" when something does not work
+var v2FaultKeys = []string{
+	"audioFaults",
+	"graphicalFaults",
+	"inputFaults",
+	"performanceFaults",
+	"saveGameFaults",
+	"significantBugs",
+	"stabilityFaults",
+	"windowingFaults",
+}
+
+// Normalize converts a V1 report. V1 reports carry their rating directly.
+func (r ReportFormatV1) Normalize(appID string) NormalizedReport {
+	normalized := NormalizedReport{
+		AppID:         appID,
+		Title:         r.Title,
+		Timestamp:     parseTimestamp(r.Timestamp),
+		Rating:        strings.ToLower(stringValue(r.Rating)),
+		ProtonVersion: stringPointerValue(r.ProtonVersion),
+		OS:            stringValue(r.OS),
+		Kernel:        stringPointerValue(r.Kernel),
+		GPU:           stringPointerValue(r.GPU),
+		GPUDriver:     stringPointerValue(r.GPUDriver),
+		CPU:           stringPointerValue(r.CPU),
+		RAM:           stringPointerValue(r.RAM),
+		Notes:         stringPointerValue(r.Notes),
+	}
+
+	normalized.RatingRank = RatingRank(normalized.Rating)
+	normalized.Proton = proton.Parse(normalized.ProtonVersion)
+	normalized.ParseHardware()
+
+	if r.Tweaks != nil {
+		normalized.Tweaks = enabledKeys(*r.Tweaks)
+	}
+
+	// Keep the answers that have no dedicated field
+	responses := make(map[string]interface{})
+	if r.Duration != nil && *r.Duration != nil {
+		responses["duration"] = *r.Duration
+	}
+	if r.Specs != nil && *r.Specs != nil {
+		responses["specs"] = *r.Specs
+	}
+	if len(responses) > 0 {
+		normalized.Responses = responses
+	}
+
+	return normalized
+}
+
+// Normalize converts a V2 report. V2 reports have no rating, it is derived from the answers by v2Rating.
+func (r ReportFormatV2) Normalize(appID string) NormalizedReport {
+	responses := r.Responses
+
+	protonVersion := responseString(responses, "protonVersion")
+	if custom := responseString(responses, "customProtonVersion"); custom != "" {
+		protonVersion = custom
+	}
+
+	normalized := NormalizedReport{
+		AppID:         appID,
+		Title:         r.App.Title,
+		Timestamp:     parseTimestamp(r.Timestamp),
+		Rating:        v2Rating(responses),
+		Verdict:       responseString(responses, "verdict"),
+		ProtonVersion: protonVersion,
+		OS:            r.SystemInfo.OS,
+		Kernel:        r.SystemInfo.Kernel,
+		GPU:           r.SystemInfo.GPU,
+		GPUDriver:     r.SystemInfo.GPUDriver,
+		CPU:           r.SystemInfo.CPU,
+		RAM:           r.SystemInfo.RAM,
+		Notes:         notesText(responses["notes"]),
+		LaunchOptions: responseString(responses, "launchOptions"),
+		Responses:     responses,
+	}
+
+	normalized.RatingRank = RatingRank(normalized.Rating)
+	normalized.Proton = proton.Parse(normalized.ProtonVersion)
+	normalized.ParseHardware()
+
+	if customizations, ok := responses["customizationsUsed"].(map[string]interface{}); ok {
+		normalized.Tweaks = enabledKeys(customizations)
+	}
+
+	return normalized
+}
+
+// ParseHardware parses the system info of the report into its structured fields
+func (n *NormalizedReport) ParseHardware() {
+	n.Hardware = hardware.Parse(n.GPU, n.GPUDriver, n.Kernel, n.OS, n.CPU, n.RAM)
+	n.GPUVendor = n.Hardware.GPU.Vendor
+	n.KernelVersion = 0
+	if n.Hardware.Kernel != nil {
+		n.KernelVersion = n.Hardware.Kernel.Code
+	}
+}
+
+// v2Rating derives a rating from the answers of a V2 report:
+//   - borked if the game does not install, open or play, or the verdict is no
+//   - platinum if nothing is faulty and no tweaks were needed
+//   - gold if nothing is faulty but tweaks were needed
+//   - silver with one or two kinds of faults, bronze with more
+func v2Rating(responses map[string]interface{}) string {
+	if responseString(responses, "verdict") == "no" ||
+		responseString(responses, "installs") == "no" ||
+		responseString(responses, "opens") == "no" ||
+		responseString(responses, "startsPlay") == "no" {
+		return RatingBorked
+	}
+
+	faults := 0
+	for _, key := range v2FaultKeys {
+		if responseString(responses, key) == "yes" {
+			faults++
+		}
+	}
+
+	tweaked := responseString(responses, "verdictOob") == "no" || responseString(responses, "launchOptions") != ""
+	if customizations, ok := responses["customizationsUsed"].(map[string]interface{}); ok && len(enabledKeys(customizations)) > 0 {
+		tweaked = true
+	}
+
+	switch {
+	case faults == 0 && !tweaked:
+		return RatingPlatinum
+	case faults == 0:
+		return RatingGold
+	case faults <= 2:
+		return RatingSilver
+	default:
+		return RatingBronze
+	}
+}
+
+// NormalizeReportData normalizes the raw data of a stored report
+func NormalizeReportData(appID string, version string, data map[string]interface{}) (*NormalizedReport, error) {
+	encoded, err := json.Marshal(canonical(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized NormalizedReport
+	if version == "V2" {
+		var report ReportFormatV2
+		if err := json.Unmarshal(encoded, &report); err != nil {
+			return nil, err
+		}
+		normalized = report.Normalize(appID)
+	} else {
+		var report ReportFormatV1
+		if err := json.Unmarshal(encoded, &report); err != nil {
+			return nil, err
+		}
+		normalized = report.Normalize(appID)
+	}
+	return &normalized, nil
+}
+
+// parseTimestamp reads unix timestamps in seconds or milliseconds, as numbers or strings, and RFC 3339 dates
+func parseTimestamp(value interface{}) time.Time {
+	var seconds float64
+	switch v := value.(type) {
+	case int64:
+		seconds = float64(v)
+	case float64:
+		seconds = v
+	case json.Number:
+		seconds, _ = v.Float64()
+	case string:
+		if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+			seconds = parsed
+		} else if parsed, err := time.Parse(time.RFC3339, v); err == nil {
+			return parsed.UTC()
+		}
+	}
+
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	if seconds > 1e12 {
+		return time.UnixMilli(int64(seconds)).UTC()
+	}
+	return time.Unix(int64(seconds), 0).UTC()
+}
+
+func responseString(responses map[string]interface{}, key string) string {
+	if responses == nil {
+		return ""
+	}
+	return stringValue(responses[key])
+}
+
+func stringValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return strings.TrimSpace(s)
+	}
+	return fmt.Sprint(value)
+}
+
+func stringPointerValue(value *interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return stringValue(*value)
+}
+
+// notesText flattens the notes of a V2 report, which are either a string or an object of notes per question
+func notesText(notes interface{}) string {
+	object, ok := notes.(map[string]interface{})
+	if !ok {
+		return stringValue(notes)
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		if text := stringValue(object[key]); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// enabledKeys returns the sorted keys of the options that are set
+func enabledKeys(options map[string]interface{}) []string {
+	var keys []string
+	for key, value := range options {
+		switch v := value.(type) {
+		case nil:
+			continue
+		case bool:
+			if !v {
+				continue
+			}
+		case string:
+			if v == "" || v == "no" || v == "false" {
+				continue
+			}
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
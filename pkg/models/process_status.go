@@ -9,4 +9,10 @@ type ProcessStatus struct {
 	ID                primitive.ObjectID `bson:"_id,omitempty"`
 	LastProcessedFile string             `bson:"last_processed_file"`
 	LastProcessedTime primitive.DateTime `bson:"last_processed_time"`
+
+	// Checkpoint of the dump being processed, empty when no dump is in progress.
+	// LastCommittedIndex is the index of the last report written to the database, -1 if none.
+	CurrentFile         string `bson:"current_file"`
+	CurrentFileChecksum string `bson:"current_file_checksum"`
+	LastCommittedIndex  int64  `bson:"last_committed_index"`
 }
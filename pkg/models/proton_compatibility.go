@@ -0,0 +1,33 @@
+package models
+
+// ProtonReleaseCount is the number of reports of a game on a Proton release, a major.minor version of a build
+type ProtonReleaseCount struct {
+	Build   string `bson:"build"`
+	Major   int    `bson:"major"`
+	Minor   int    `bson:"minor"`
+	Reports int64  `bson:"reports"`
+	// Working is the number of those reports rated silver or better
+	Working int64 `bson:"working"`
+}
+
+// ProtonRelease is how a game runs on a Proton release
+type ProtonRelease struct {
+	Version      string  `json:"version"`
+	Reports      int64   `json:"reports"`
+	Working      int64   `json:"working"`
+	WorkingShare float64 `json:"workingShare"`
+}
+
+// ProtonBuildCompatibility is how a game runs on the releases of a Proton build, oldest release first.
+// WorksSince is the release from which on the game works, empty if it does not work on the latest releases.
+type ProtonBuildCompatibility struct {
+	Build      string          `json:"build"`
+	WorksSince string          `json:"worksSince,omitempty"`
+	Releases   []ProtonRelease `json:"releases"`
+}
+
+// ProtonCompatibility is how a game runs on each Proton build it was reported on
+type ProtonCompatibility struct {
+	AppID  string                     `json:"appId"`
+	Builds []ProtonBuildCompatibility `json:"builds"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Components of the setup regressions are detected across
+const (
+	RegressionProton = "proton"
+	RegressionDriver = "driver"
+	RegressionKernel = "kernel"
+)
+
+// VersionRatings sums up the ratings of the reports of a game on a version of a component, as rating points
+// from borked 0 to platinum 4. Group is the Proton build or the driver vendor, versions only compare within one.
+type VersionRatings struct {
+	AppID   string `bson:"appId"`
+	Group   string `bson:"group"`
+	Major   int    `bson:"major"`
+	Minor   int    `bson:"minor"`
+	Reports int64  `bson:"reports"`
+	// Points and PointsSquared are the sums of the points and of their squares, for the mean and variance
+	Points        float64 `bson:"points"`
+	PointsSquared float64 `bson:"pointsSquared"`
+}
+
+// Regression is a significant drop of the ratings of a game from a version of a component to the next one.
+// Scores are the mean rating points scaled to 0–1, PValue the one-sided p-value of the drop.
+type Regression struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	AppID       string             `bson:"appId" json:"appId"`
+	Title       string             `bson:"title" json:"title"`
+	Component   string             `bson:"component" json:"component"`
+	Group       string             `bson:"group,omitempty" json:"group,omitempty"`
+	FromVersion string             `bson:"fromVersion" json:"fromVersion"`
+	ToVersion   string             `bson:"toVersion" json:"toVersion"`
+	FromReports int64              `bson:"fromReports" json:"fromReports"`
+	ToReports   int64              `bson:"toReports" json:"toReports"`
+	FromScore   float64            `bson:"fromScore" json:"fromScore"`
+	ToScore     float64            `bson:"toScore" json:"toScore"`
+	Drop        float64            `bson:"drop" json:"drop"`
+	PValue      float64            `bson:"pValue" json:"pValue"`
+	DetectedAt  time.Time          `bson:"detectedAt" json:"detectedAt"`
+	// RunID is the detection run the regression was found in
+	RunID primitive.ObjectID `bson:"runId" json:"-"`
+}
+
+// RegressionRun records the last regression detection and the thresholds it ran with
+type RegressionRun struct {
+	// RunID is the run the listed regressions belong to, zero for the runs stored before regressions had one
+	RunID       primitive.ObjectID `bson:"runId,omitempty" json:"-"`
+	DetectedAt  time.Time          `bson:"detectedAt" json:"detectedAt"`
+	MinReports  int64              `bson:"minReports" json:"minReports"`
+	MinDrop     float64            `bson:"minDrop" json:"minDrop"`
+	MaxPValue   float64            `bson:"maxPValue" json:"maxPValue"`
+	Regressions int                `bson:"regressions" json:"regressions"`
+}
@@ -4,8 +4,24 @@ import "go.mongodb.org/mongo-driver/bson/primitive"
 
 type Report struct {
 	ID            primitive.ObjectID     `bson:"_id,omitempty"`
+	AppID         string                 `bson:"appId,omitempty"`
+	GameID        primitive.ObjectID     `bson:"gameId,omitempty"`
 	Data          map[string]interface{} `bson:"data"`
 	ReportVersion string                 `bson:"report_version"`
+	Hash          string                 `bson:"hash,omitempty"`
+	Normalized    *NormalizedReport      `bson:"normalized,omitempty"`
+}
+
+// NewReport wraps the raw data of a report with its normalized form and computes its content hash.
+// The game ID is set once the game is stored.
+func NewReport(appID string, data map[string]interface{}, reportVersion string, normalized NormalizedReport) *Report {
+	return &Report{
+		AppID:         appID,
+		Data:          data,
+		ReportVersion: reportVersion,
+		Hash:          HashReport(appID, reportVersion, data),
+		Normalized:    &normalized,
+	}
 }
 
 type ReportFormatV2 struct {
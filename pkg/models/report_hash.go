@@ -0,0 +1,102 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var scientificNotation = regexp.MustCompile(`^[-+]?[0-9]*\.?[0-9]+([eE][-+]?[0-9]+)?$`)
+
+// NormalizeAppID turns app IDs written in scientific notation by some old reports, like "2.9203e+05", into plain integers
+func NormalizeAppID(appID string) (string, error) {
+	if !scientificNotation.MatchString(appID) {
+		return appID, nil
+	}
+
+	parsedAppID, err := strconv.ParseFloat(appID, 64)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(int(parsedAppID)), nil
+}
+
+// ReportAppID returns the normalized app ID stored in the raw data of a report
+func ReportAppID(version string, data map[string]interface{}) (string, error) {
+	var appID interface{}
+	if version == "V2" {
+		if app, ok := canonical(data["app"]).(map[string]interface{}); ok {
+			if steam, ok := app["steam"].(map[string]interface{}); ok {
+				appID = steam["appId"]
+			}
+		}
+	} else {
+		appID = data["appId"]
+	}
+
+	if appID == nil {
+		return "", fmt.Errorf("report has no app ID")
+	}
+	return NormalizeAppID(fmt.Sprint(appID))
+}
+
+// HashReport returns the content hash identifying a report. It covers the app ID, the timestamp,
+// the system information and the responses, that is everything in the raw data but the app
+// title, which changes over time. Reports with the same hash are duplicates of each other.
+func HashReport(appID string, version string, data map[string]interface{}) string {
+	identity := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		identity[key] = canonical(value)
+	}
+
+	if version == "V2" {
+		delete(identity, "app")
+	} else {
+		delete(identity, "appId")
+		delete(identity, "title")
+	}
+	identity["appId"] = appID
+
+	// json.Marshal sorts map keys, which makes the encoding deterministic
+	encoded, _ := json.Marshal(identity)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonical converts the BSON document types the driver decodes into plain maps and slices,
+// so a report read back from the database hashes like the one decoded from the dump
+func canonical(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[key] = canonical(item)
+		}
+		return converted
+	case primitive.M:
+		return canonical(map[string]interface{}(v))
+	case primitive.D:
+		return canonical(v.Map())
+	case primitive.A:
+		return canonical([]interface{}(v))
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = canonical(item)
+		}
+		return converted
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case int:
+		return float64(v)
+	default:
+		return v
+	}
+}
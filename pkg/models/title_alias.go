@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// TitleAlias is a title reports of a game were filed under, how many and when
+type TitleAlias struct {
+	Title     string    `bson:"title" json:"title"`
+	Count     int64     `bson:"count" json:"count"`
+	FirstSeen time.Time `bson:"firstSeen" json:"firstSeen"`
+	LastSeen  time.Time `bson:"lastSeen" json:"lastSeen"`
+}
+
+// AddTitleAlias merges an observation of a title into the aliases: its count is added and its
+// first and last seen times widen those of the alias with the same title, if there is one
+func AddTitleAlias(aliases []TitleAlias, observed TitleAlias) []TitleAlias {
+	for i, alias := range aliases {
+		if alias.Title != observed.Title {
+			continue
+		}
+		aliases[i].Count += observed.Count
+		if observed.FirstSeen.Before(alias.FirstSeen) {
+			aliases[i].FirstSeen = observed.FirstSeen
+		}
+		if observed.LastSeen.After(alias.LastSeen) {
+			aliases[i].LastSeen = observed.LastSeen
+		}
+		return aliases
+	}
+	return append(aliases, observed)
+}
+
+// CanonicalTitle returns the title a game is listed under: the alias seen most often, the one seen
+// last on a tie. It returns an empty string when there are no aliases.
+func CanonicalTitle(aliases []TitleAlias) string {
+	var canonical *TitleAlias
+	for i, alias := range aliases {
+		if canonical == nil || alias.Count > canonical.Count ||
+			(alias.Count == canonical.Count && alias.LastSeen.After(canonical.LastSeen)) {
+			canonical = &aliases[i]
+		}
+	}
+	if canonical == nil {
+		return ""
+	}
+	return canonical.Title
+}
@@ -0,0 +1,108 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the last item of a page, the next page starts right after it.
+// Clients get it as an opaque string and must not rely on what is inside.
+type Cursor struct {
+	ID primitive.ObjectID `json:"id"`
+	// Sort and Value are the sort of the listing and the sort value of the last item, when not sorted by ID
+	Sort  string          `json:"sort,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+func (c Cursor) Encode() string {
+	encoded, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil || cursor.ID.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// Params are the pagination parameters of a request
+type Params struct {
+	Limit  int
+	Cursor *Cursor
+}
+
+// After returns the ID the page starts after, the zero ID for the first page
+func (p Params) After() primitive.ObjectID {
+	if p.Cursor == nil {
+		return primitive.NilObjectID
+	}
+	return p.Cursor.ID
+}
+
+// CheckSort fails with ErrInvalidCursor if the cursor was issued for a listing with another sort
+func (p Params) CheckSort(sort string) error {
+	if p.Cursor != nil && p.Cursor.Sort != sort {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// ParseParams reads limit and cursor from the query string. The limit defaults to DefaultLimit
+// and must be between 1 and MaxLimit.
+func ParseParams(query url.Values) (Params, error) {
+	params := Params{Limit: DefaultLimit}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return params, fmt.Errorf("limit must be a number between 1 and %d", MaxLimit)
+		}
+		params.Limit = limit
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := DecodeCursor(value)
+		if err != nil {
+			return params, err
+		}
+		params.Cursor = cursor
+	}
+
+	return params, nil
+}
+
+// SetNext points the client to the next page with a Link header and the bare cursor in X-Next-Cursor.
+// Nothing is set on the last page, where next is empty.
+func SetNext(w http.ResponseWriter, r *http.Request, next string) {
+	if next == "" {
+		return
+	}
+
+	query := r.URL.Query()
+	query.Set("cursor", next)
+	nextURL := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+
+	w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", nextURL.String()))
+	w.Header().Set("X-Next-Cursor", next)
+}
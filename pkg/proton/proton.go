@@ -0,0 +1,200 @@
+// Package proton parses the Proton versions of reports into a build and a comparable version.
+//
+// Reports name the Proton version the way the Steam client or the compatibility tool does, e.g.
+// "Proton 8.0-3", "8.0-3", "Experimental", "GE-Proton8-25" or "Proton-6.21-GE-2". Versions of every
+// build share one encoding, so GE-Proton8-25 is 8.0.25 and compares after Proton 8.0-3.
+package proton
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Builds of Proton
+const (
+	BuildOfficial     = "official"
+	BuildExperimental = "experimental"
+	BuildGE           = "ge"
+	BuildTKG          = "tkg"
+	BuildOther        = "other"
+)
+
+// Builds lists the builds, official first
+var Builds = []string{BuildOfficial, BuildExperimental, BuildGE, BuildTKG, BuildOther}
+
+// Version is a parsed Proton version. Major and Minor are the Proton release the build is based on,
+// Patch the revision of the build: 8.0-3 is 8.0.3, GE-Proton8-25 is 8.0.25.
+type Version struct {
+	Build string `bson:"build" json:"build"`
+	Major int    `bson:"major" json:"major"`
+	Minor int    `bson:"minor" json:"minor"`
+	Patch int    `bson:"patch" json:"patch"`
+	// Code is major*1000000 + minor*1000 + patch, 0 for builds without a version such as Experimental
+	Code int64 `bson:"code" json:"-"`
+}
+
+var (
+	// GE-Proton8-25, GE-Proton7-43
+	gePattern = regexp.MustCompile(`(?i)ge-?proton-?(\d+)(?:[.-](\d+))?`)
+	// Proton-6.21-GE-2, 5.9-GE-8-ST, proton-ge-custom 6.1-GE-2
+	legacyGEPattern = regexp.MustCompile(`(?i)(\d+)\.(\d+)-ge(?:-(\d+))?`)
+	geNamePattern   = regexp.MustCompile(`(?i)\bge\b|-ge-|proton-?ge|ge-?proton`)
+	tkgPattern      = regexp.MustCompile(`(?i)tkg`)
+	experimental    = regexp.MustCompile(`(?i)experimental|bleeding.?edge`)
+	// 8.0-3, 7.0-6, 5.13-6, 3.16-9 Beta, 9.0 (beta)
+	releasePattern = regexp.MustCompile(`(\d+)\.(\d+)(?:[-.](\d+))?`)
+	otherPattern   = regexp.MustCompile(`(?i)[a-z]`)
+)
+
+// Parse classifies a Proton version and extracts its version. Unparsable versions are BuildOther
+// without a version, and an empty string parses to the zero Version.
+func Parse(value string) Version {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Version{}
+	}
+
+	switch {
+	case tkgPattern.MatchString(value):
+		return withRelease(BuildTKG, releasePattern.FindStringSubmatch(value))
+	case geNamePattern.MatchString(value):
+		if match := gePattern.FindStringSubmatch(value); match != nil {
+			// GE-Proton8-25 is the 25th GE build on Proton 8
+			return newVersion(BuildGE, match[1], "", match[2])
+		}
+		if match := legacyGEPattern.FindStringSubmatch(value); match != nil {
+			return newVersion(BuildGE, match[1], match[2], match[3])
+		}
+		return withRelease(BuildGE, releasePattern.FindStringSubmatch(value))
+	case experimental.MatchString(value):
+		return withRelease(BuildExperimental, releasePattern.FindStringSubmatch(value))
+	}
+
+	match := releasePattern.FindStringSubmatch(value)
+	if match == nil {
+		return Version{Build: BuildOther}
+	}
+	// Anything besides the release, "Proton" and a beta or hotfix tag is a custom build
+	rest := strings.NewReplacer(match[0], "", "proton", "", "Proton", "", "beta", "", "Beta", "", "hotfix", "", "Hotfix", "").Replace(value)
+	if otherPattern.MatchString(rest) {
+		return withRelease(BuildOther, match)
+	}
+	return withRelease(BuildOfficial, match)
+}
+
+func withRelease(build string, match []string) Version {
+	if match == nil {
+		return Version{Build: build}
+	}
+	return newVersion(build, match[1], match[2], match[3])
+}
+
+func newVersion(build, major, minor, patch string) Version {
+	version := Version{Build: build}
+	version.Major, _ = strconv.Atoi(major)
+	version.Minor, _ = strconv.Atoi(minor)
+	version.Patch, _ = strconv.Atoi(patch)
+	if version.Major > 999999 || version.Minor > 999 || version.Patch > 999 {
+		return Version{Build: build}
+	}
+	version.Code = code(version.Major, version.Minor, version.Patch)
+	return version
+}
+
+func code(major, minor, patch int) int64 {
+	return int64(major)*1000000 + int64(minor)*1000 + int64(patch)
+}
+
+// Series names the release a version belongs to, the way its build names it: "8.0", "GE-Proton8",
+// "6.21-GE", "TKG 7.2". Versions without a version are named after their build.
+func Series(build string, major, minor int) string {
+	if major == 0 && minor == 0 {
+		switch build {
+		case BuildExperimental:
+			return "Experimental"
+		case BuildGE:
+			return "GE-Proton"
+		case BuildTKG:
+			return "TKG"
+		case BuildOther:
+			return "Other"
+		}
+		return ""
+	}
+
+	release := fmt.Sprintf("%d.%d", major, minor)
+	switch build {
+	case BuildGE:
+		if minor == 0 {
+			return fmt.Sprintf("GE-Proton%d", major)
+		}
+		return release + "-GE"
+	case BuildTKG:
+		return "TKG " + release
+	case BuildExperimental:
+		return "Experimental " + release
+	case BuildOther:
+		return "Other " + release
+	}
+	return release
+}
+
+// Operators of version comparisons
+var operators = []string{">=", "<=", "==", ">", "<", "="}
+
+// ParseComparison reads a comparison such as ">=8.0" or "<7" into inclusive bounds on version codes,
+// 0 for an open bound. A version without a patch or minor covers all of them: >8.0 is after every
+// 8.0 release, <=7 includes 7.0-6 and 7.1.
+func ParseComparison(comparison string) (int64, int64, error) {
+	comparison = strings.TrimSpace(comparison)
+	operator := ""
+	for _, candidate := range operators {
+		if strings.HasPrefix(comparison, candidate) {
+			operator = candidate
+			break
+		}
+	}
+	if operator == "" {
+		return 0, 0, fmt.Errorf("invalid version comparison: %s, must start with one of %s", comparison, strings.Join(operators, " "))
+	}
+
+	value := strings.TrimSpace(strings.TrimPrefix(comparison, operator))
+	parts := strings.FieldsFunc(value, func(r rune) bool { return r == '.' || r == '-' })
+	if len(parts) == 0 || len(parts) > 3 {
+		return 0, 0, fmt.Errorf("invalid Proton version: %s, use a version such as 8.0 or 7.0-6", value)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 || (i > 0 && number > 999) {
+			return 0, 0, fmt.Errorf("invalid Proton version: %s, use a version such as 8.0 or 7.0-6", value)
+		}
+		numbers[i] = number
+	}
+
+	low := code(numbers[0], numbers[1], numbers[2])
+	high := low
+	switch len(parts) {
+	case 1:
+		high += 999999
+	case 2:
+		high += 999
+	}
+
+	switch operator {
+	case ">=":
+		return low, 0, nil
+	case ">":
+		return high + 1, 0, nil
+	case "<=":
+		return 0, high, nil
+	case "<":
+		if low <= 1 {
+			return 0, 0, fmt.Errorf("no Proton version is lower than %s", value)
+		}
+		return 0, low - 1, nil
+	}
+	return low, high, nil
+}
@@ -0,0 +1,104 @@
+package proton
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Version
+	}{
+		{"", Version{}},
+		{"  ", Version{}},
+		{"Proton 8.0-3", Version{BuildOfficial, 8, 0, 3, 8000003}},
+		{"8.0-3", Version{BuildOfficial, 8, 0, 3, 8000003}},
+		{"5.13-6", Version{BuildOfficial, 5, 13, 6, 5013006}},
+		{"3.16-9 Beta", Version{BuildOfficial, 3, 16, 9, 3016009}},
+		{"9.0 (beta)", Version{BuildOfficial, 9, 0, 0, 9000000}},
+		{"Experimental", Version{Build: BuildExperimental}},
+		{"Proton Experimental", Version{Build: BuildExperimental}},
+		{"Bleeding Edge", Version{Build: BuildExperimental}},
+		{"GE-Proton8-25", Version{BuildGE, 8, 0, 25, 8000025}},
+		{"GE-Proton7-43", Version{BuildGE, 7, 0, 43, 7000043}},
+		{"ge-proton9", Version{BuildGE, 9, 0, 0, 9000000}},
+		{"Proton-6.21-GE-2", Version{BuildGE, 6, 21, 2, 6021002}},
+		{"5.9-GE-8-ST", Version{BuildGE, 5, 9, 8, 5009008}},
+		{"proton-ge-custom 6.1-GE-2", Version{BuildGE, 6, 1, 2, 6001002}},
+		{"Proton-tkg 7.2", Version{BuildTKG, 7, 2, 0, 7002000}},
+		{"Luxtorpeda 2.1", Version{BuildOther, 2, 1, 0, 2001000}},
+		{"custom", Version{Build: BuildOther}},
+		{"8.1000", Version{Build: BuildOfficial}},
+	}
+	for _, test := range tests {
+		if got := Parse(test.value); got != test.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestParseOrdersAcrossBuilds(t *testing.T) {
+	ordered := []string{"Proton 7.0-6", "Proton 8.0-3", "GE-Proton8-25", "Proton 9.0-2", "Proton 10.0-1"}
+	for i := 1; i < len(ordered); i++ {
+		if before, after := Parse(ordered[i-1]), Parse(ordered[i]); before.Code >= after.Code {
+			t.Errorf("%s (%d) does not compare before %s (%d)", ordered[i-1], before.Code, ordered[i], after.Code)
+		}
+	}
+}
+
+func TestSeries(t *testing.T) {
+	tests := []struct {
+		build        string
+		major, minor int
+		want         string
+	}{
+		{BuildOfficial, 8, 0, "8.0"},
+		{BuildOfficial, 0, 0, ""},
+		{BuildGE, 8, 0, "GE-Proton8"},
+		{BuildGE, 6, 21, "6.21-GE"},
+		{BuildGE, 0, 0, "GE-Proton"},
+		{BuildTKG, 7, 2, "TKG 7.2"},
+		{BuildTKG, 0, 0, "TKG"},
+		{BuildExperimental, 9, 0, "Experimental 9.0"},
+		{BuildExperimental, 0, 0, "Experimental"},
+		{BuildOther, 2, 1, "Other 2.1"},
+		{BuildOther, 0, 0, "Other"},
+	}
+	for _, test := range tests {
+		if got := Series(test.build, test.major, test.minor); got != test.want {
+			t.Errorf("Series(%s, %d, %d) = %q, want %q", test.build, test.major, test.minor, got, test.want)
+		}
+	}
+}
+
+func TestParseComparison(t *testing.T) {
+	tests := []struct {
+		comparison string
+		min, max   int64
+		wantErr    bool
+	}{
+		{">=8.0", 8000000, 0, false},
+		{">8.0", 8001000, 0, false},
+		{">8", 9000000, 0, false},
+		{"<=7", 0, 7999999, false},
+		{"<=7.0-6", 0, 7000006, false},
+		{"<8.0", 0, 7999999, false},
+		{"=8.0", 8000000, 8000999, false},
+		{"==8.0-3", 8000003, 8000003, false},
+		{"= 6.21", 6021000, 6021999, false},
+		{"8.0", 0, 0, true},
+		{">=", 0, 0, true},
+		{">=eight", 0, 0, true},
+		{">=8.1000", 0, 0, true},
+		{">=8.0-3-1", 0, 0, true},
+		{"<0", 0, 0, true},
+	}
+	for _, test := range tests {
+		min, max, err := ParseComparison(test.comparison)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseComparison(%q) error = %v, want error %v", test.comparison, err, test.wantErr)
+			continue
+		}
+		if min != test.min || max != test.max {
+			t.Errorf("ParseComparison(%q) = %d, %d, want %d, %d", test.comparison, min, max, test.min, test.max)
+		}
+	}
+}
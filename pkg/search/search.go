@@ -0,0 +1,336 @@
+package search
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Entry is a game as the index knows it
+type Entry struct {
+	ID    primitive.ObjectID
+	AppID string
+	Title string
+	// Aliases are the other titles the game is found under
+	Aliases []string
+	// Reports is the number of reports of the game, suggestions rank popular games first
+	Reports int64
+}
+
+// titles returns the distinct titles of the entry, its own title first
+func (e Entry) titles() []string {
+	titles := []string{e.Title}
+	for _, alias := range e.Aliases {
+		if alias != e.Title {
+			titles = append(titles, alias)
+		}
+	}
+	return titles
+}
+
+// Match is a game matching a query, with the score it matched with
+type Match struct {
+	ID    primitive.ObjectID
+	AppID string
+	Title string
+	Score float64
+}
+
+// Scores of a query word against a title word. A word found as typed scores 1, a prefix of a word or a word
+// within a few typos scores less, the closer to the title word the higher.
+const (
+	prefixBaseScore = 0.5
+	typoMaxScore    = 0.9
+	// coverageScore is added in proportion of the title words matched, so closer titles rank first
+	coverageScore = 0.5
+)
+
+// Index is an in-memory title index supporting prefix matching and typo tolerance.
+// It is immutable once built, a new one is built to take changes in.
+type Index struct {
+	entries []Entry
+	// titles holds every title and alias of the entries
+	titles []indexedTitle
+	// words is the sorted vocabulary of the titles, postings[i] the titles containing words[i]
+	words    []string
+	postings [][]int
+	// trigrams maps the trigrams of the words to the words containing them, to find typo candidates
+	trigrams map[string][]int
+	// suggestions are the normalized titles and their suffixes starting at each word, sorted
+	suggestions []suggestionKey
+}
+
+type indexedTitle struct {
+	entry int
+	words int
+}
+
+// NewIndex builds an index over the given entries
+func NewIndex(entries []Entry) *Index {
+	index := &Index{
+		entries:  entries,
+		trigrams: make(map[string][]int),
+	}
+
+	postings := make(map[string][]int)
+	for i, entry := range entries {
+		for _, title := range entry.titles() {
+			words := Tokenize(title)
+			for _, word := range words {
+				postings[word] = append(postings[word], len(index.titles))
+			}
+			index.titles = append(index.titles, indexedTitle{entry: i, words: len(words)})
+			index.suggestions = append(index.suggestions, suggestionKeys(title, i)...)
+		}
+	}
+	sort.Slice(index.suggestions, func(i, j int) bool {
+		return index.suggestions[i].key < index.suggestions[j].key
+	})
+
+	index.words = make([]string, 0, len(postings))
+	for word := range postings {
+		index.words = append(index.words, word)
+	}
+	sort.Strings(index.words)
+
+	index.postings = make([][]int, len(index.words))
+	for i, word := range index.words {
+		index.postings[i] = postings[word]
+		for _, gram := range trigramsOf(word) {
+			index.trigrams[gram] = append(index.trigrams[gram], i)
+		}
+	}
+	return index
+}
+
+// Len returns the number of entries in the index
+func (idx *Index) Len() int {
+	return len(idx.entries)
+}
+
+// Search returns the entries matching the query with a score of at least precision, best first,
+// then by _id. Each query word adds up to 1 to the score of the titles it matches, see the score constants.
+// An entry scores as its best matching title or alias.
+func (idx *Index) Search(query string, precision float64) []Match {
+	terms := Tokenize(query)
+	if len(terms) == 0 {
+		return []Match{}
+	}
+
+	scores := make(map[int]float64)
+	matchedWords := make(map[int]int)
+	for _, term := range terms {
+		// The best score of the term for each title, a term counts once per title
+		best := make(map[int]float64)
+		for word, score := range idx.wordMatches(term) {
+			for _, title := range idx.postings[word] {
+				if score > best[title] {
+					best[title] = score
+				}
+			}
+		}
+		for title, score := range best {
+			scores[title] += score
+			matchedWords[title]++
+		}
+	}
+
+	entryScores := make(map[int]float64)
+	for i, score := range scores {
+		title := idx.titles[i]
+		if title.words > 0 {
+			coverage := float64(matchedWords[i]) / float64(title.words)
+			if coverage > 1 {
+				coverage = 1
+			}
+			score += coverageScore * coverage
+		}
+		if score > entryScores[title.entry] {
+			entryScores[title.entry] = score
+		}
+	}
+
+	matches := []Match{}
+	for i, score := range entryScores {
+		if score < precision {
+			continue
+		}
+		entry := idx.entries[i]
+		matches = append(matches, Match{ID: entry.ID, AppID: entry.AppID, Title: entry.Title, Score: score})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return bytes.Compare(matches[i].ID[:], matches[j].ID[:]) < 0
+	})
+	return matches
+}
+
+// wordMatches returns the words of the vocabulary matching a query term, with their score
+func (idx *Index) wordMatches(term string) map[int]float64 {
+	matches := make(map[int]float64)
+	termLength := len([]rune(term))
+
+	// Words starting with the term, the term itself included
+	start := sort.SearchStrings(idx.words, term)
+	for i := start; i < len(idx.words) && strings.HasPrefix(idx.words[i], term); i++ {
+		wordLength := len([]rune(idx.words[i]))
+		matches[i] = prefixBaseScore + (1-prefixBaseScore)*float64(termLength)/float64(wordLength)
+	}
+
+	maxEdits := maxEditsFor(term)
+	if maxEdits == 0 {
+		return matches
+	}
+
+	// Words sharing enough trigrams with the term to be within maxEdits of it. Each edit changes up to 3
+	// trigrams, a transposition of adjacent characters up to 4.
+	grams := trigramsOf(term)
+	shared := make(map[int]int)
+	for _, gram := range grams {
+		for _, word := range idx.trigrams[gram] {
+			shared[word]++
+		}
+	}
+	minShared := len(grams) - 4*maxEdits
+	if minShared < 1 {
+		minShared = 1
+	}
+	for word, count := range shared {
+		if count < minShared {
+			continue
+		}
+		if _, ok := matches[word]; ok {
+			continue
+		}
+		wordRunes := []rune(idx.words[word])
+		if abs(len(wordRunes)-termLength) > maxEdits {
+			continue
+		}
+		distance := editDistance([]rune(term), wordRunes)
+		if distance > maxEdits {
+			continue
+		}
+		longest := termLength
+		if len(wordRunes) > longest {
+			longest = len(wordRunes)
+		}
+		matches[word] = typoMaxScore * (1 - float64(distance)/float64(longest))
+	}
+	return matches
+}
+
+// maxEditsFor returns the number of typos tolerated in a term: none in short terms and numbers
+// (2077 is not 2078), one from 4 characters and two from 8
+func maxEditsFor(term string) int {
+	isNumber := true
+	for _, r := range term {
+		if !unicode.IsDigit(r) {
+			isNumber = false
+			break
+		}
+	}
+	length := len([]rune(term))
+	switch {
+	case isNumber || length < 4:
+		return 0
+	case length < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Tokenize splits a text into its distinct lowercase words, made of letters and digits
+func Tokenize(text string) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	for _, field := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !seen[field] {
+			seen[field] = true
+			tokens = append(tokens, field)
+		}
+	}
+	return tokens
+}
+
+// trigramsOf returns the trigrams of a word padded with ^ and $, so its start and end count too
+func trigramsOf(word string) []string {
+	runes := []rune("^" + word + "$")
+	var grams []string
+	for i := 0; i+3 <= len(runes); i++ {
+		grams = append(grams, string(runes[i:i+3]))
+	}
+	return grams
+}
+
+// editDistance returns the optimal string alignment distance of a and b: the number of insertions,
+// deletions, substitutions and transpositions of adjacent characters turning a into b
+func editDistance(a, b []rune) int {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(a)][len(b)]
+}
+
+func min(values ...int) int {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+var (
+	mu      sync.RWMutex
+	current = NewIndex(nil)
+)
+
+// SetIndex replaces the index used by the rest of the application
+func SetIndex(index *Index) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = index
+}
+
+// GetIndex returns the index used by the rest of the application
+func GetIndex() *Index {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
@@ -0,0 +1,161 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testIndex() *Index {
+	return NewIndex([]Entry{
+		{ID: primitive.NewObjectID(), AppID: "1091500", Title: "Cyberpunk 2077", Reports: 300},
+		{ID: primitive.NewObjectID(), AppID: "1145360", Title: "Hades", Reports: 200},
+		{ID: primitive.NewObjectID(), AppID: "730", Title: "Counter-Strike 2", Aliases: []string{"Counter-Strike: Global Offensive"}, Reports: 900},
+		{ID: primitive.NewObjectID(), AppID: "379720", Title: "DOOM", Reports: 100},
+		{ID: primitive.NewObjectID(), AppID: "2280", Title: "DOOM II", Reports: 50},
+		{ID: primitive.NewObjectID(), AppID: "292030", Title: "The Witcher 3: Wild Hunt", Reports: 400},
+	})
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"hades", "hades", 0},
+		{"", "doom", 4},
+		{"doom", "", 4},
+		{"hades", "hadea", 1},
+		{"witcher", "wticher", 1},
+		{"witcher", "witchr", 1},
+		{"witcher", "witchers", 1},
+		{"cyberpunk", "cybrepnuk", 2},
+		{"ca", "abc", 3},
+	}
+	for _, test := range tests {
+		if got := editDistance([]rune(test.a), []rune(test.b)); got != test.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestMaxEditsFor(t *testing.T) {
+	tests := []struct {
+		term string
+		want int
+	}{
+		{"cs", 0},
+		{"doo", 0},
+		{"2077", 0},
+		{"doom", 1},
+		{"witcher", 1},
+		{"cyberpunk", 2},
+		{"ÿôûñ", 1},
+	}
+	for _, test := range tests {
+		if got := maxEditsFor(test.term); got != test.want {
+			t.Errorf("maxEditsFor(%q) = %d, want %d", test.term, got, test.want)
+		}
+	}
+}
+
+func TestTrigramsOf(t *testing.T) {
+	tests := []struct {
+		word string
+		want []string
+	}{
+		{"a", []string{"^a$"}},
+		{"doom", []string{"^do", "doo", "oom", "om$"}},
+		{"été", []string{"^ét", "été", "té$"}},
+	}
+	for _, test := range tests {
+		if got := trigramsOf(test.word); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("trigramsOf(%q) = %v, want %v", test.word, got, test.want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"The Witcher 3: Wild Hunt", []string{"the", "witcher", "3", "wild", "hunt"}},
+		{"Counter-Strike", []string{"counter", "strike"}},
+		{"DOOM doom", []string{"doom"}},
+	}
+	for _, test := range tests {
+		if got := Tokenize(test.text); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Tokenize(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	index := testIndex()
+	tests := []struct {
+		name  string
+		query string
+		// want is the app IDs of the matches, best first
+		want []string
+	}{
+		{"exact title", "hades", []string{"1145360"}},
+		{"prefix", "cyber", []string{"1091500"}},
+		{"transposed letters", "wticher", []string{"292030"}},
+		{"two typos in a long word", "cybrepnuk", []string{"1091500"}},
+		{"no typos in numbers", "2078", []string{}},
+		{"no typos in short words", "dom", []string{}},
+		{"closer title first", "doom", []string{"379720", "2280"}},
+		{"alias", "global offensive", []string{"730"}},
+		{"nothing to search", "!!", []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matches := index.Search(test.query, 0.5)
+			got := []string{}
+			for _, match := range matches {
+				got = append(got, match.AppID)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Search(%q) = %v, want %v", test.query, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSearchReturnsOwnTitleOfAliases(t *testing.T) {
+	matches := testIndex().Search("counter strike global", 1)
+	if len(matches) != 1 || matches[0].Title != "Counter-Strike 2" {
+		t.Errorf("Search = %v, want Counter-Strike 2", matches)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	index := testIndex()
+	tests := []struct {
+		name  string
+		query string
+		limit int
+		want  []string
+	}{
+		{"title start by reports", "doo", 10, []string{"DOOM", "DOOM II"}},
+		{"later word", "str", 10, []string{"Counter-Strike 2"}},
+		{"whole last word", "doom ", 10, []string{"DOOM II"}},
+		{"alias", "counter strike g", 10, []string{"Counter-Strike 2"}},
+		{"limit", "d", 1, []string{"DOOM"}},
+		{"no words", "  ", 10, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := []string{}
+			for _, suggestion := range index.Suggest(test.query, test.limit) {
+				got = append(got, suggestion.Title)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Suggest(%q) = %v, want %v", test.query, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Suggestion is a game whose title completes a query
+type Suggestion struct {
+	AppID string `json:"appId"`
+	Title string `json:"title"`
+}
+
+type suggestionKey struct {
+	// key is a suffix of the normalized title, sharing its memory
+	key   string
+	entry int
+	// atStart is set for the whole title, which ranks before a match on a later word
+	atStart bool
+}
+
+// suggestionKeys returns the keys a title is suggested under: the whole normalized title and its suffixes
+// starting at each later word, so "str" suggests Counter-Strike
+func suggestionKeys(title string, entry int) []suggestionKey {
+	normalized := normalize(title)
+	if normalized == "" {
+		return nil
+	}
+
+	keys := []suggestionKey{{key: normalized, entry: entry, atStart: true}}
+	for i := 0; i < len(normalized); i++ {
+		if normalized[i] == ' ' {
+			keys = append(keys, suggestionKey{key: normalized[i+1:], entry: entry})
+		}
+	}
+	return keys
+}
+
+// Suggest returns up to limit titles completing the query: titles starting with it first, then titles with
+// a later word starting with it, each by descending number of reports, then shorter titles first.
+// The last word of the query may be partial, the others must match whole words. Games are also suggested
+// under their aliases, with their own title.
+func (idx *Index) Suggest(query string, limit int) []Suggestion {
+	prefix := normalize(query)
+	if prefix == "" {
+		return []Suggestion{}
+	}
+
+	// A title matching at its start and at a later word is ranked by its best match
+	atStart := make(map[int]bool)
+	start := sort.Search(len(idx.suggestions), func(i int) bool {
+		return idx.suggestions[i].key >= prefix
+	})
+	for i := start; i < len(idx.suggestions) && strings.HasPrefix(idx.suggestions[i].key, prefix); i++ {
+		key := idx.suggestions[i]
+		atStart[key.entry] = atStart[key.entry] || key.atStart
+	}
+
+	entries := make([]int, 0, len(atStart))
+	for entry := range atStart {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := idx.entries[entries[i]], idx.entries[entries[j]]
+		switch {
+		case atStart[entries[i]] != atStart[entries[j]]:
+			return atStart[entries[i]]
+		case a.Reports != b.Reports:
+			return a.Reports > b.Reports
+		case len(a.Title) != len(b.Title):
+			return len(a.Title) < len(b.Title)
+		}
+		return a.Title < b.Title
+	})
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	suggestions := make([]Suggestion, 0, len(entries))
+	for _, entry := range entries {
+		suggestions = append(suggestions, Suggestion{AppID: idx.entries[entry].AppID, Title: idx.entries[entry].Title})
+	}
+	return suggestions
+}
+
+// normalize lowercases a text and keeps its words of letters and digits, separated by single spaces.
+// A trailing separator is kept so "doom " does not complete to "doomsday".
+func normalize(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	normalized := strings.Join(words, " ")
+	last, _ := utf8.DecodeLastRuneInString(text)
+	if normalized != "" && !unicode.IsLetter(last) && !unicode.IsDigit(last) {
+		normalized += " "
+	}
+	return normalized
+}
@@ -3,9 +3,11 @@ package server
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/trsnaqe/protondb-api/pkg/api"
+	"github.com/trsnaqe/protondb-api/pkg/services/stats_service"
 )
 
 type Server struct {
@@ -17,11 +19,21 @@ func NewServer() *Server {
 		router: mux.NewRouter().StrictSlash(true),
 	}
 
+	server.router.Use(recordRequests)
 	api.SetupRoutes(server.router)
 
 	return server
 }
 
+// recordRequests counts the requests served and their response times for /api/stats
+func recordRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		stats_service.RecordRequest(time.Since(start))
+	})
+}
+
 func (s *Server) Run(addr string) {
 	log.Printf("Server started at %s\n", addr)
 	log.Fatal(http.ListenAndServe(addr, s.router))
@@ -0,0 +1,60 @@
+package analytics_service
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/services/games_service"
+	"github.com/trsnaqe/protondb-api/pkg/storage"
+)
+
+// datasetTop is how many of the most common games, GPUs, distros and Proton versions are kept
+const datasetTop = 20
+
+// RefreshDatasetStats computes the analytics over all reports and stores them, so /api/stats only reads them.
+// It is run after each ingestion, as the dataset only changes then.
+func RefreshDatasetStats() error {
+	start := time.Now()
+
+	stats, err := storage.GetStore().ComputeDatasetStats(datasetTop)
+	if err != nil {
+		return err
+	}
+
+	// Tiers are those of the game summaries, computed the way the summary endpoint does
+	tiers := make(map[string]int64)
+	err = storage.GetStore().StreamGameRatings(func(appID string, ratings []models.ReportRating) error {
+		tiers[games_service.ComputeGameSummary(ratings).Tier]++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	stats.Tiers = make([]models.StatCount, 0, len(tiers))
+	for tier, count := range tiers {
+		stats.Tiers = append(stats.Tiers, models.StatCount{Value: tier, Count: count})
+	}
+	sort.Slice(stats.Tiers, func(i, j int) bool {
+		if stats.Tiers[i].Count != stats.Tiers[j].Count {
+			return stats.Tiers[i].Count > stats.Tiers[j].Count
+		}
+		return stats.Tiers[i].Value < stats.Tiers[j].Value
+	})
+
+	if err := storage.GetStore().SaveDatasetStats(stats); err != nil {
+		return err
+	}
+	log.Printf("Dataset stats computed in %s", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// EnsureDatasetStats computes the dataset analytics if they were never stored, e.g. on a new database
+func EnsureDatasetStats() error {
+	stats, err := storage.GetStore().GetDatasetStats()
+	if err != nil || stats != nil {
+		return err
+	}
+	return RefreshDatasetStats()
+}
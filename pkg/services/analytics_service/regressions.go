@@ -0,0 +1,266 @@
+package analytics_service
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/hardware"
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/pagination"
+	"github.com/trsnaqe/protondb-api/pkg/proton"
+	"github.com/trsnaqe/protondb-api/pkg/storage"
+)
+
+// RegressionConfig holds the thresholds a rating drop between two versions must pass to be a regression
+type RegressionConfig struct {
+	// MinReports is the number of reports each of the two versions needs
+	MinReports int64
+	// MinDrop is the drop of the mean rating, scaled to 0–1 like game summary scores
+	MinDrop float64
+	// MaxPValue is the significance level of the one-sided test of the drop
+	MaxPValue float64
+}
+
+var regressionConfig = RegressionConfig{MinReports: 10, MinDrop: 0.1, MaxPValue: 0.05}
+
+// SetRegressionConfig sets the thresholds regressions are detected with, zero fields keep their value
+func SetRegressionConfig(config RegressionConfig) {
+	if config.MinReports > 0 {
+		regressionConfig.MinReports = config.MinReports
+	}
+	if config.MinDrop > 0 {
+		regressionConfig.MinDrop = config.MinDrop
+	}
+	if config.MaxPValue > 0 {
+		regressionConfig.MaxPValue = config.MaxPValue
+	}
+}
+
+// GetRegressionConfig returns the thresholds regressions are detected with
+func GetRegressionConfig() RegressionConfig {
+	return regressionConfig
+}
+
+var regressionComponents = []string{models.RegressionProton, models.RegressionDriver, models.RegressionKernel}
+
+// DetectRegressions compares the ratings of every game between consecutive versions of Proton, GPU drivers
+// and kernels, and stores the significant drops, largest first. Versions with fewer than MinReports reports
+// are skipped, so consecutive means consecutive among the versions with enough reports. Like the dataset
+// stats, it is run after each ingestion.
+func DetectRegressions() error {
+	start := time.Now()
+	config := GetRegressionConfig()
+
+	var regressions []models.Regression
+	for _, component := range regressionComponents {
+		ratings, err := storage.GetStore().GetVersionRatings(component)
+		if err != nil {
+			return err
+		}
+		regressions = append(regressions, componentRegressions(component, ratings, config)...)
+	}
+
+	if err := setRegressionTitles(regressions); err != nil {
+		return err
+	}
+	sort.Slice(regressions, func(i, j int) bool {
+		if regressions[i].Drop != regressions[j].Drop {
+			return regressions[i].Drop > regressions[j].Drop
+		}
+		if regressions[i].PValue != regressions[j].PValue {
+			return regressions[i].PValue < regressions[j].PValue
+		}
+		return regressions[i].AppID < regressions[j].AppID
+	})
+
+	run := models.RegressionRun{
+		DetectedAt:  start.UTC(),
+		MinReports:  config.MinReports,
+		MinDrop:     config.MinDrop,
+		MaxPValue:   config.MaxPValue,
+		Regressions: len(regressions),
+	}
+	for i := range regressions {
+		regressions[i].DetectedAt = run.DetectedAt
+	}
+	if err := storage.GetStore().SaveRegressions(regressions, run); err != nil {
+		return err
+	}
+	log.Printf("Detected %d regressions in %s", len(regressions), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// EnsureRegressions detects regressions if they were never detected, e.g. on a new database
+func EnsureRegressions() error {
+	run, err := storage.GetStore().GetRegressionRun()
+	if err != nil || run != nil {
+		return err
+	}
+	return DetectRegressions()
+}
+
+// versionSample is the ratings of a game on a version, as rating points
+type versionSample struct {
+	name     string
+	order    int64
+	reports  int64
+	mean     float64
+	variance float64
+}
+
+// componentRegressions finds the regressions in the ratings of a component, per game and group
+func componentRegressions(component string, ratings []models.VersionRatings, config RegressionConfig) []models.Regression {
+	type series struct{ appID, group string }
+	samples := make(map[series]map[string]*models.VersionRatings)
+	for _, rating := range ratings {
+		key := series{rating.AppID, rating.Group}
+		if samples[key] == nil {
+			samples[key] = make(map[string]*models.VersionRatings)
+		}
+		// NVIDIA releases driver branches by major version, the minor is the build within the branch
+		if component == models.RegressionDriver && rating.Group == hardware.DriverNvidia {
+			rating.Minor = 0
+		}
+		name := versionName(component, rating.Group, rating.Major, rating.Minor)
+		if merged, ok := samples[key][name]; ok {
+			merged.Reports += rating.Reports
+			merged.Points += rating.Points
+			merged.PointsSquared += rating.PointsSquared
+			continue
+		}
+		copied := rating
+		samples[key][name] = &copied
+	}
+
+	var regressions []models.Regression
+	for key, versions := range samples {
+		var sampled []versionSample
+		for name, version := range versions {
+			if version.Reports < config.MinReports {
+				continue
+			}
+			mean := version.Points / float64(version.Reports)
+			variance := 0.0
+			if version.Reports > 1 {
+				// Sample variance from the sums, clamped against rounding below 0
+				variance = math.Max(0, (version.PointsSquared-mean*version.Points)/float64(version.Reports-1))
+			}
+			sampled = append(sampled, versionSample{
+				name:     name,
+				order:    int64(version.Major)*1000 + int64(version.Minor),
+				reports:  version.Reports,
+				mean:     mean,
+				variance: variance,
+			})
+		}
+		sort.Slice(sampled, func(i, j int) bool { return sampled[i].order < sampled[j].order })
+
+		for i := 1; i < len(sampled); i++ {
+			from, to := sampled[i-1], sampled[i]
+			drop := (from.mean - to.mean) / 4
+			if drop < config.MinDrop {
+				continue
+			}
+			pValue := dropPValue(from, to)
+			if pValue > config.MaxPValue {
+				continue
+			}
+			regressions = append(regressions, models.Regression{
+				AppID:       key.appID,
+				Component:   component,
+				Group:       key.group,
+				FromVersion: from.name,
+				ToVersion:   to.name,
+				FromReports: from.reports,
+				ToReports:   to.reports,
+				FromScore:   math.Round(from.mean/4*100) / 100,
+				ToScore:     math.Round(to.mean/4*100) / 100,
+				Drop:        math.Round(drop*100) / 100,
+				PValue:      math.Round(pValue*10000) / 10000,
+			})
+		}
+	}
+	return regressions
+}
+
+// dropPValue is the one-sided p-value of the mean of to being lower than the mean of from, with a z-test
+// on the difference of the means. Without any variance the drop is certain.
+func dropPValue(from, to versionSample) float64 {
+	standardError := math.Sqrt(from.variance/float64(from.reports) + to.variance/float64(to.reports))
+	if standardError == 0 {
+		return 0
+	}
+	z := (from.mean - to.mean) / standardError
+	return 0.5 * math.Erfc(z/math.Sqrt2)
+}
+
+// versionName names a version the way it is known: "8.0" or "GE-Proton8" for Proton, "535" for NVIDIA
+// drivers, "23.1" for Mesa, "6.1" for kernels
+func versionName(component, group string, major, minor int) string {
+	switch {
+	case component == models.RegressionProton:
+		return proton.Series(group, major, minor)
+	case component == models.RegressionDriver && group == hardware.DriverNvidia:
+		return fmt.Sprint(major)
+	}
+	return fmt.Sprintf("%d.%d", major, minor)
+}
+
+// setRegressionTitles sets the title of the game of each regression
+func setRegressionTitles(regressions []models.Regression) error {
+	if len(regressions) == 0 {
+		return nil
+	}
+	games, err := storage.GetStore().GetAllGames()
+	if err != nil {
+		return err
+	}
+	titles := make(map[string]string, len(games))
+	for _, game := range games {
+		if game.Title != nil {
+			titles[game.AppID] = *game.Title
+		}
+	}
+	for i := range regressions {
+		regressions[i].Title = titles[regressions[i].AppID]
+	}
+	return nil
+}
+
+// ListRegressions returns a page of the detected regressions matching the query, largest drop first,
+// and the cursor of the next page, empty on the last page
+func ListRegressions(query storage.RegressionQuery, page pagination.Params) ([]models.Regression, string, error) {
+	// Ask for one more regression to know whether there is a next page
+	query.After = page.After()
+	query.Limit = page.Limit + 1
+	regressions, err := storage.GetStore().ListRegressions(query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(regressions) > page.Limit {
+		regressions = regressions[:page.Limit]
+		next = pagination.Cursor{ID: regressions[page.Limit-1].ID}.Encode()
+	}
+	return regressions, next, nil
+}
+
+// GetGameRegressions returns the detected regressions of a game matching the query, largest drop first.
+// It returns nil if there is no game with the app ID.
+func GetGameRegressions(appID string, query storage.RegressionQuery) ([]models.Regression, error) {
+	game, err := storage.GetStore().GetGameByAppID(appID)
+	if err != nil {
+		return nil, err
+	}
+	if game == nil {
+		return nil, nil
+	}
+
+	query.AppID = appID
+	query.Limit = 0
+	return storage.GetStore().ListRegressions(query)
+}
@@ -2,65 +2,105 @@ package background_services
 
 import (
 	"log"
-	"os"
 	"time"
 
 	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/services/analytics_service"
+	"github.com/trsnaqe/protondb-api/pkg/services/games_service"
 	"github.com/trsnaqe/protondb-api/pkg/storage"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func ProcessReportsBackground(updateInterval time.Duration) {
-	processStatus, err := storage.GetLastProcessStatus()
+	processStatus, err := storage.GetStore().GetLastProcessStatus()
 	if err != nil {
 		log.Fatalf("Failed to get process status: %v", err)
 	}
 
 	if processStatus == nil {
 		processStatus = &models.ProcessStatus{
-			LastProcessedFile: "reports_oct31_2019.tar.gz",
-			LastProcessedTime: primitive.NewDateTimeFromTime(time.Now()),
+			LastProcessedFile:  "reports_oct31_2019.tar.gz",
+			LastProcessedTime:  primitive.NewDateTimeFromTime(time.Now()),
+			LastCommittedIndex: -1,
 		}
-		err = storage.CreateProcessStatus(processStatus)
+		err = storage.GetStore().CreateProcessStatus(processStatus)
 		if err != nil {
 			log.Fatalf("Failed to create process status: %v", err)
 		}
 	}
 
+	// Finish the dump an earlier run was interrupted in right away instead of waiting for the next tick
+	if processStatus.CurrentFile != "" {
+		log.Println("Resuming interrupted processing of", processStatus.CurrentFile)
+		processLatestReportFile(processStatus)
+	}
+
 	ticker := time.NewTicker(updateInterval)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		jsonData, newLastProcessedFile, extractedDir, err := GetLatestProcessedReportFile(processStatus.LastProcessedFile)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		if newLastProcessedFile != processStatus.LastProcessedFile {
-			err = ProcessReportFile(jsonData)
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			err = os.RemoveAll(extractedDir)
-			if err != nil {
-				log.Println("Error removing directory:", err)
-			}
+		processLatestReportFile(processStatus)
+	}
+}
 
-			// Update the last processed file in the database
-			processStatus.LastProcessedFile = newLastProcessedFile
-			processStatus.LastProcessedTime = primitive.NewDateTimeFromTime(time.Now())
-			err = storage.UpdateProcessStatus(processStatus)
-			if err != nil {
-				log.Fatalf("Failed to update process status: %v", err)
-			}
+// processLatestReportFile processes the next dump, resuming from the checkpoint in processStatus if
+// it is the dump that was in progress. Errors are logged and the checkpoint kept for the next run.
+func processLatestReportFile(processStatus *models.ProcessStatus) {
+	dump, err := GetLatestProcessedReportFile(processStatus.LastProcessedFile)
+	if err != nil {
+		log.Println("Error getting the latest report file:", err)
+		return
+	}
 
-			SetLastTickTime(time.Now())
+	if dump == nil {
+		SetLastTickTime(time.Now())
+		return
+	}
+	defer dump.Close()
 
+	var resumeFrom int64
+	if processStatus.CurrentFile == dump.File && processStatus.CurrentFileChecksum == dump.Checksum {
+		resumeFrom = processStatus.LastCommittedIndex + 1
+	} else {
+		processStatus.CurrentFile = dump.File
+		processStatus.CurrentFileChecksum = dump.Checksum
+		processStatus.LastCommittedIndex = -1
+		if err := storage.GetStore().UpdateProcessStatus(processStatus); err != nil {
+			log.Println("Failed to update process status:", err)
+			return
 		}
-		if processStatus.LastProcessedFile == newLastProcessedFile {
-			SetLastTickTime(time.Now())
-		}
+	}
+
+	err = ProcessReportFile(dump.File, dump, resumeFrom, func(lastCommittedIndex int64) error {
+		processStatus.LastCommittedIndex = lastCommittedIndex
+		return storage.GetStore().UpdateProcessStatus(processStatus)
+	})
+	// Committed batches may have added games, titles and reports even if the dump failed halfway
+	if err := games_service.RebuildSearchIndex(); err != nil {
+		log.Println("Error rebuilding the search index:", err)
+	}
+	if err := analytics_service.RefreshDatasetStats(); err != nil {
+		log.Println("Error computing the dataset stats:", err)
+	}
+	if err := analytics_service.DetectRegressions(); err != nil {
+		log.Println("Error detecting regressions:", err)
+	}
+	if err != nil {
+		log.Printf("Error processing %s, will resume after report %d: %v", dump.File, processStatus.LastCommittedIndex, err)
+		return
+	}
 
+	// Update the last processed file in the database
+	processStatus.LastProcessedFile = dump.File
+	processStatus.LastProcessedTime = primitive.NewDateTimeFromTime(time.Now())
+	processStatus.CurrentFile = ""
+	processStatus.CurrentFileChecksum = ""
+	processStatus.LastCommittedIndex = -1
+	err = storage.GetStore().UpdateProcessStatus(processStatus)
+	if err != nil {
+		log.Println("Failed to update process status:", err)
+		return
 	}
+
+	SetLastTickTime(time.Now())
 }
@@ -3,18 +3,13 @@ package background_services
 import (
 	"archive/tar"
 	"compress/gzip"
-	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
-
-	"github.com/google/go-github/github"
+	"time"
 )
 
 const (
@@ -22,180 +17,128 @@ const (
 	rawBaseURL = "https://raw.githubusercontent.com/bdefore/protondb-data/master"
 )
 
-func GetLatestProcessedReportFile(lastProcessedFile string) ([]byte, string, string, error) {
-	// Get the list of files in the reports directory
-	fileList, err := getFileList(lastProcessedFile)
-	if err != nil || len(fileList) == 0 {
-		log.Println("Error getting file list:", err)
-		return nil, "", "", err
-	}
-
-	// Find the oldest file that is newer than the last processed file
-	var oldestFile string
-	if lastProcessedFile == "" {
-		// If lastProcessedFile is empty, get the oldest file in the fileList
-		oldestFile = fileList[0]
-	} else {
-		for _, file := range fileList {
-			// Check if the file is newer than the last processed file
-			if compareFiles(lastProcessedFile, file) {
-				oldestFile = file
-				break
-			}
-		}
-
-		// Check if an older file was found
-		if oldestFile == "" {
-			log.Println("No new file to download. Using the last processed file:", lastProcessedFile)
-			return nil, lastProcessedFile, "", nil
-		}
-	}
-
-	fileURL := fmt.Sprintf("%s/%s", rawBaseURL, oldestFile)
-	log.Println("Downloading file:", fileURL)
-	resp, err := http.Get(fileURL)
-	if err != nil {
-		log.Println("Error downloading file:", err)
-		return nil, lastProcessedFile, "", err
-	}
-	defer resp.Body.Close()
+// ReportDump is an opened dump: a stream of its JSON content along with the file name and checksum
+type ReportDump struct {
+	io.ReadCloser
+	File     string
+	Checksum string
+}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Error downloading file. Status code: %d\n", resp.StatusCode)
-		return nil, lastProcessedFile, "", fmt.Errorf("failed to download file. Status code: %d", resp.StatusCode)
-	}
+// GetLatestProcessedReportFile opens the next dump to process after lastProcessedFile.
+// If there is no new dump it returns nil.
+func GetLatestProcessedReportFile(lastProcessedFile string) (*ReportDump, error) {
+	source := GetReportSource()
 
-	tempFile, err := ioutil.TempFile("", "report")
+	// Get the list of files in the reports directory
+	fileList, err := source.ListFiles()
 	if err != nil {
-		log.Println("Error creating temporary file:", err)
-		return nil, lastProcessedFile, "", err
+		log.Println("Error getting file list:", err)
+		return nil, err
 	}
-	defer tempFile.Close()
 
-	_, err = io.Copy(tempFile, resp.Body)
-	if err != nil {
-		log.Println("Error saving downloaded file:", err)
-		return nil, lastProcessedFile, "", err
+	nextFile := selectNextFile(fileList, lastProcessedFile)
+	if nextFile == "" {
+		log.Println("No new file to download. Using the last processed file:", lastProcessedFile)
+		return nil, nil
 	}
 
-	extractedDir, err := extractTarGz(tempFile.Name())
+	checksum, err := source.Checksum(nextFile)
 	if err != nil {
-		log.Println("Error extracting .tar.gz file:", err)
-		return nil, lastProcessedFile, "", err
+		log.Println("Error getting file checksum:", err)
+		return nil, err
 	}
 
-	err = os.Remove(tempFile.Name())
+	log.Println("Opening file:", nextFile)
+	reader, err := source.Open(nextFile)
 	if err != nil {
-		log.Println("Error removing .tar.gz file:", err)
+		log.Println("Error opening file:", err)
+		return nil, err
 	}
 
-	jsonFilePath, err := findJSONFile(extractedDir)
-	if err != nil {
-		log.Println("Error finding JSON file:", err)
-		return nil, lastProcessedFile, extractedDir, err
+	// Already extracted dumps can be read as they are
+	if !strings.HasSuffix(nextFile, ".tar.gz") {
+		return &ReportDump{ReadCloser: reader, File: nextFile, Checksum: checksum}, nil
 	}
 
-	jsonData, err := ioutil.ReadFile(jsonFilePath)
+	jsonReader, err := openJSONInTarGz(reader)
 	if err != nil {
-		log.Println("Error reading JSON file:", err)
-		return nil, lastProcessedFile, extractedDir, err
+		log.Println("Error reading .tar.gz file:", err)
+		reader.Close()
+		return nil, err
 	}
 
-	log.Println("Processed JSON file:", jsonFilePath)
-	return jsonData, oldestFile, extractedDir, nil
+	return &ReportDump{ReadCloser: jsonReader, File: nextFile, Checksum: checksum}, nil
 }
 
-func getFileList(latestProcessedFile string) ([]string, error) {
-	client := github.NewClient(nil)
-	ctx := context.Background()
-
-	tree, _, err := client.Git.GetTree(ctx, "bdefore", "protondb-data", "master", true)
-	if err != nil {
-		return nil, err
+// selectNextFile picks the dump to process after lastProcessedFile, or "" if there is nothing new.
+// Dumps are cumulative, so only nov1_2019 (which includes the old reports) and the newest dump are needed.
+func selectNextFile(fileList []string, lastProcessedFile string) string {
+	if len(fileList) == 0 {
+		return ""
 	}
 
-	var fileList []string
-	for _, entry := range tree.Entries {
-		if entry.GetType() == "blob" && strings.HasPrefix(entry.GetPath(), "reports/") && strings.HasSuffix(entry.GetPath(), ".tar.gz") {
-			fileList = append(fileList, entry.GetPath())
+	novDate := time.Date(2019, time.November, 1, 0, 0, 0, 0, time.UTC)
+
+	// If lastProcessedFile is older than nov1_2019, process nov1_2019 as it includes the old reports
+	if lastDate, err := dateFromFile(lastProcessedFile); err == nil && lastDate.Before(novDate) {
+		for _, file := range fileList {
+			if date, err := dateFromFile(file); err == nil && date.Equal(novDate) {
+				return file
+			}
 		}
 	}
 
-	sort.Slice(fileList, func(i, j int) bool {
-		return compareFiles(fileList[i], fileList[j])
-	})
-
-	novFile := "reports/reports_nov1_2019.tar.gz"
-
-	// If latestProcessedFile is older than nov1_2019, return nov1_2019 as it includes the old reports
-	if compareFiles(latestProcessedFile, novFile) {
-		return []string{novFile}, nil
+	// Otherwise process the newest file as it already includes reports in-between
+	newestFile := fileList[len(fileList)-1]
+	if lastProcessedFile == "" || compareFiles(lastProcessedFile, newestFile) {
+		return newestFile
 	}
+	return ""
+}
 
-	// If latestProcessedFile is not older than nov1_2019, return the newest file as it already includes reports in-between
-	newestFile := fileList[len(fileList)-1]
-	return []string{newestFile}, nil
+// tarEntryReader reads a single entry of a tar archive and closes the underlying archive when done
+type tarEntryReader struct {
+	io.Reader
+	closers []io.Closer
 }
 
-// extractTarGz extracts the .tar.gz archive and returns the path to the extracted directory
-func extractTarGz(tarGzFile string) (string, error) {
-	file, err := os.Open(tarGzFile)
-	if err != nil {
-		return "", err
+func (t *tarEntryReader) Close() error {
+	var err error
+	for _, closer := range t.closers {
+		if closeErr := closer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
 	}
-	defer file.Close()
+	return err
+}
 
-	gzReader, err := gzip.NewReader(file)
+// openJSONInTarGz advances the .tar.gz archive read from r to its JSON file and returns a stream of it,
+// so the dump never has to be extracted to disk or held in memory.
+func openJSONInTarGz(r io.ReadCloser) (io.ReadCloser, error) {
+	gzReader, err := gzip.NewReader(r)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer gzReader.Close()
 
 	tarReader := tar.NewReader(gzReader)
-
-	extractedDir, err := ioutil.TempDir("", "extracted")
-	if err != nil {
-		return "", err
-	}
-
 	for {
 		header, err := tarReader.Next()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			log.Println("Error reading tar entry:", err)
-			continue // Skip this entry and continue with the next one
+			gzReader.Close()
+			return nil, err
 		}
 
-		targetPath := filepath.Join(extractedDir, header.Name)
-
-		if header.Typeflag == tar.TypeDir {
-			err := os.MkdirAll(targetPath, 0755)
-			if err != nil {
-				log.Println("Error creating directory:", err)
-			}
-			continue
-		}
-
-		file, err := os.Create(targetPath)
-		if err != nil {
-			log.Println("Error creating file:", err)
-			continue // Skip this file and continue with the next one
-		}
-
-		_, err = io.Copy(file, tarReader)
-		file.Close()
-
-		if err != nil {
-			log.Println("Error writing file:", err)
-			// If there was an error writing the file, delete it to avoid partial extraction
-			if err := os.Remove(targetPath); err != nil {
-				log.Println("Error removing partial file:", err)
-			}
+		if header.Typeflag == tar.TypeReg && strings.HasSuffix(header.Name, ".json") {
+			log.Println("Found JSON file:", header.Name)
+			return &tarEntryReader{Reader: tarReader, closers: []io.Closer{gzReader, r}}, nil
 		}
 	}
-	return extractedDir, nil
+
+	gzReader.Close()
+	return nil, fmt.Errorf("JSON file not found")
 }
 
 // findJSONFile finds the JSON file in the specified directory and returns its path.
@@ -4,17 +4,11 @@ import (
 	"fmt"
 	"log"
 	"regexp"
-	"strconv"
 	"time"
 )
 
 var lastTickTime = time.Now()
 
-func isScientificNotation(str string) bool {
-	r := regexp.MustCompile(`^[-+]?[0-9]*\.?[0-9]+([eE][-+]?[0-9]+)?$`)
-	return r.MatchString(str)
-}
-
 // compareFiles compares two file names based on month and year
 func compareFiles(file1, file2 string) bool {
 	date1, err := dateFromFile(file1)
@@ -50,16 +44,6 @@ func dateFromFile(file string) (time.Time, error) {
 	return t, nil
 }
 
-func convertScientificNotation(appID string) (int, error) {
-	parsedAppID, err := strconv.ParseFloat(appID, 64)
-	if err != nil {
-		return 0, err
-	}
-	appIDInt := int(parsedAppID)
-
-	return appIDInt, nil
-}
-
 var updateInterval time.Duration // Define the update interval as a package-level variable
 
 // SetUpdateInterval sets the update interval used by the background process
@@ -0,0 +1,92 @@
+package background_services
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// IngestProgress is a snapshot of the counters of the current or last ingestion run
+type IngestProgress struct {
+	Running   bool      `json:"running"`
+	File      string    `json:"file"`
+	StartedAt time.Time `json:"startedAt"`
+	Workers   int       `json:"workers"`
+	Decoded   int64     `json:"decoded"`
+	Inserted  int64     `json:"inserted"`
+	Skipped   int64     `json:"skipped"`
+	Failed    int64     `json:"failed"`
+	Batches   int64     `json:"batches"`
+}
+
+// ingestCounters are updated concurrently by the workers of the report pool
+type ingestCounters struct {
+	mu        sync.RWMutex
+	running   bool
+	file      string
+	startedAt time.Time
+	workers   int
+
+	decoded  int64
+	inserted int64
+	skipped  int64
+	failed   int64
+	batches  int64
+}
+
+var progress ingestCounters
+
+func (c *ingestCounters) start(file string, workers int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.running = true
+	c.file = file
+	c.startedAt = time.Now()
+	c.workers = workers
+	atomic.StoreInt64(&c.decoded, 0)
+	atomic.StoreInt64(&c.inserted, 0)
+	atomic.StoreInt64(&c.skipped, 0)
+	atomic.StoreInt64(&c.failed, 0)
+	atomic.StoreInt64(&c.batches, 0)
+}
+
+func (c *ingestCounters) finish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.running = false
+}
+
+func (c *ingestCounters) addDecoded() {
+	atomic.AddInt64(&c.decoded, 1)
+}
+
+// nextBatch returns the number of the next batch written in this run
+func (c *ingestCounters) nextBatch() int {
+	return int(atomic.AddInt64(&c.batches, 1))
+}
+
+func (c *ingestCounters) addResult(result BatchResult) {
+	atomic.AddInt64(&c.inserted, int64(result.Inserted))
+	atomic.AddInt64(&c.skipped, int64(result.Skipped))
+	atomic.AddInt64(&c.failed, int64(result.Failed))
+}
+
+// GetIngestProgress returns the counters of the current or last ingestion run
+func GetIngestProgress() IngestProgress {
+	progress.mu.RLock()
+	defer progress.mu.RUnlock()
+
+	return IngestProgress{
+		Running:   progress.running,
+		File:      progress.file,
+		StartedAt: progress.startedAt,
+		Workers:   progress.workers,
+		Decoded:   atomic.LoadInt64(&progress.decoded),
+		Inserted:  atomic.LoadInt64(&progress.inserted),
+		Skipped:   atomic.LoadInt64(&progress.skipped),
+		Failed:    atomic.LoadInt64(&progress.failed),
+		Batches:   atomic.LoadInt64(&progress.batches),
+	}
+}
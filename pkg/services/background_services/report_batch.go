@@ -0,0 +1,148 @@
+package background_services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/storage"
+)
+
+var batchSize = 1000
+
+// SetBatchSize sets how many reports are written to the database at once during ingestion
+func SetBatchSize(size int) {
+	if size > 0 {
+		batchSize = size
+	}
+}
+
+// GetBatchSize returns how many reports are written to the database at once during ingestion
+func GetBatchSize() int {
+	return batchSize
+}
+
+// pendingReport is a decoded report waiting for its batch to be written
+type pendingReport struct {
+	appID  string
+	title  string
+	report *models.Report
+}
+
+// titleAlias returns the observation of the title of the report, seen when the report was filed
+func (r pendingReport) titleAlias() models.TitleAlias {
+	seen := time.Now().UTC()
+	if r.report.Normalized != nil && !r.report.Normalized.Timestamp.IsZero() {
+		seen = r.report.Normalized.Timestamp
+	}
+	return models.TitleAlias{Title: r.title, Count: 1, FirstSeen: seen, LastSeen: seen}
+}
+
+// BatchResult describes the outcome of writing one batch of reports
+type BatchResult struct {
+	Batch    int
+	Inserted int
+	Skipped  int
+	Failed   int
+}
+
+// reportBatcher accumulates reports and writes them with a handful of bulk operations per batch
+type reportBatcher struct {
+	size    int
+	pending []pendingReport
+	total   BatchResult
+}
+
+func newReportBatcher(size int) *reportBatcher {
+	return &reportBatcher{size: size, pending: make([]pendingReport, 0, size)}
+}
+
+// add queues the report and writes the batch once it is full
+func (b *reportBatcher) add(report pendingReport) error {
+	b.pending = append(b.pending, report)
+	if len(b.pending) >= b.size {
+		return b.flush()
+	}
+	return nil
+}
+
+// flush writes the queued reports: one bulk upsert for their games, one InsertMany for the reports
+// referencing them, then one bulk update adding the titles of the inserted reports to the aliases of their
+// games. Duplicates are skipped, other reports that fail to insert are logged and counted; failures affecting
+// the whole batch are returned.
+func (b *reportBatcher) flush() error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	result := BatchResult{Batch: progress.nextBatch()}
+	store := storage.GetStore()
+
+	newTitles := make(map[string]string)
+	for _, report := range b.pending {
+		if _, ok := newTitles[report.appID]; !ok {
+			newTitles[report.appID] = report.title
+		}
+	}
+	games, err := store.UpsertGames(newTitles)
+	if err != nil {
+		log.Printf("Batch %d: error upserting %d games: %v", result.Batch, len(newTitles), err)
+		return err
+	}
+
+	var queued []pendingReport
+	var reports []*models.Report
+	for _, report := range b.pending {
+		game, ok := games[report.appID]
+		if !ok {
+			log.Printf("Batch %d: game %s missing after upsert", result.Batch, report.appID)
+			result.Failed++
+			continue
+		}
+
+		report.report.GameID = game.ID
+		queued = append(queued, report)
+		reports = append(reports, report.report)
+	}
+
+	failed := map[int]error{}
+	err = store.InsertReports(reports)
+	if err != nil {
+		var bulkErr *storage.BulkWriteError
+		if !errors.As(err, &bulkErr) {
+			log.Printf("Batch %d: error inserting %d reports: %v", result.Batch, len(reports), err)
+			return err
+		}
+		failed = bulkErr.Failed
+	}
+
+	// Only the inserted reports count towards the aliases, duplicates were counted when first ingested
+	titles := make(map[string][]models.TitleAlias)
+	for i, report := range queued {
+		if reportErr, ok := failed[i]; ok {
+			// Reports already in the database are rejected by the unique index on their hash
+			if errors.Is(reportErr, storage.ErrDuplicateReport) {
+				result.Skipped++
+			} else {
+				log.Printf("Batch %d: error inserting report of game %s: %v", result.Batch, report.appID, reportErr)
+				result.Failed++
+			}
+			continue
+		}
+		result.Inserted++
+		titles[report.appID] = models.AddTitleAlias(titles[report.appID], report.titleAlias())
+	}
+
+	if err := store.AddTitleAliases(titles); err != nil {
+		log.Printf("Batch %d: error adding the titles of %d games: %v", result.Batch, len(titles), err)
+		return err
+	}
+
+	log.Printf("Batch %d: %d inserted, %d skipped, %d failed", result.Batch, result.Inserted, result.Skipped, result.Failed)
+	b.total.Inserted += result.Inserted
+	b.total.Skipped += result.Skipped
+	b.total.Failed += result.Failed
+	progress.addResult(result)
+	b.pending = b.pending[:0]
+	return nil
+}
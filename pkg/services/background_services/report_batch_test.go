@@ -0,0 +1,75 @@
+package background_services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/storage"
+)
+
+func pending(appID string, title string, key int, day int) pendingReport {
+	normalized := models.NormalizedReport{
+		Rating:    models.RatingGold,
+		Timestamp: time.Date(2023, 1, day, 0, 0, 0, 0, time.UTC),
+	}
+	report := models.NewReport(appID, map[string]interface{}{"appId": appID, "key": key}, "V2", normalized)
+	return pendingReport{appID: appID, title: title, report: report}
+}
+
+func TestReportBatcherSkipsDuplicateTitles(t *testing.T) {
+	storage.SetStore(storage.NewMemoryStore())
+
+	ingest := func() BatchResult {
+		batcher := newReportBatcher(10)
+		for _, report := range []pendingReport{
+			pending("10", "Hades", 1, 1),
+			pending("10", "HADES", 2, 2),
+			pending("10", "HADES", 3, 3),
+			pending("20", "DOOM", 4, 1),
+		} {
+			if err := batcher.add(report); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := batcher.flush(); err != nil {
+			t.Fatal(err)
+		}
+		return batcher.total
+	}
+
+	tests := []struct {
+		name   string
+		result BatchResult
+	}{
+		{"first ingestion", BatchResult{Inserted: 4}},
+		{"same batch again", BatchResult{Skipped: 4}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := ingest(); result != test.result {
+				t.Errorf("result = %+v, want %+v", result, test.result)
+			}
+
+			game, err := storage.GetStore().GetGameByAppID("10")
+			if err != nil || game == nil {
+				t.Fatalf("GetGameByAppID = %v, %v", game, err)
+			}
+			if *game.Title != "HADES" {
+				t.Errorf("title = %q, want HADES", *game.Title)
+			}
+			want := map[string]models.TitleAlias{
+				"Hades": {Title: "Hades", Count: 1, FirstSeen: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), LastSeen: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+				"HADES": {Title: "HADES", Count: 2, FirstSeen: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), LastSeen: time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC)},
+			}
+			if len(game.Titles) != len(want) {
+				t.Fatalf("aliases = %v, want %v", game.Titles, want)
+			}
+			for _, alias := range game.Titles {
+				if !alias.FirstSeen.Equal(want[alias.Title].FirstSeen) || !alias.LastSeen.Equal(want[alias.Title].LastSeen) || alias.Count != want[alias.Title].Count {
+					t.Errorf("alias %+v, want %+v", alias, want[alias.Title])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package background_services
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+var workerCount = 4
+
+// SetWorkerCount sets how many workers process reports in parallel during ingestion
+func SetWorkerCount(count int) {
+	if count > 0 {
+		workerCount = count
+	}
+}
+
+// GetWorkerCount returns how many workers process reports in parallel during ingestion
+func GetWorkerCount() int {
+	return workerCount
+}
+
+// shardMessage carries either a report to process or, when commit is set, a request to write the pending batch
+type shardMessage struct {
+	report pendingReport
+	commit chan<- error
+}
+
+// reportPool is a bounded pool of workers, each owning a batcher. Reports are sharded by app ID so
+// all reports of a game go through the same worker in file order, which keeps title updates consistent.
+type reportPool struct {
+	shards   []chan shardMessage
+	batchers []*reportBatcher
+	wg       sync.WaitGroup
+
+	errMu sync.Mutex
+	err   error
+}
+
+func newReportPool(workers, batchSize int) *reportPool {
+	pool := &reportPool{
+		shards:   make([]chan shardMessage, workers),
+		batchers: make([]*reportBatcher, workers),
+	}
+
+	for i := 0; i < workers; i++ {
+		pool.shards[i] = make(chan shardMessage, batchSize)
+		pool.batchers[i] = newReportBatcher(batchSize)
+
+		pool.wg.Add(1)
+		go pool.work(pool.shards[i], pool.batchers[i])
+	}
+
+	return pool
+}
+
+func (p *reportPool) work(shard chan shardMessage, batcher *reportBatcher) {
+	defer p.wg.Done()
+
+	for message := range shard {
+		if message.commit != nil {
+			if p.failed() == nil {
+				if err := batcher.flush(); err != nil {
+					p.fail(err)
+				}
+			}
+			message.commit <- p.failed()
+			continue
+		}
+
+		// Keep draining after a failure so the reader never blocks
+		if p.failed() != nil {
+			continue
+		}
+		if err := batcher.add(message.report); err != nil {
+			p.fail(err)
+		}
+	}
+
+	if p.failed() == nil {
+		if err := batcher.flush(); err != nil {
+			p.fail(err)
+		}
+	}
+}
+
+// submit hands the report to the worker owning its game, or returns the error that stopped the pool
+func (p *reportPool) submit(report pendingReport) error {
+	if err := p.failed(); err != nil {
+		return err
+	}
+
+	p.shards[shardOf(report.appID, len(p.shards))] <- shardMessage{report: report}
+	return nil
+}
+
+// commit makes every worker write its pending batch and waits for them, so all reports
+// submitted so far are in the database once it returns without error
+func (p *reportPool) commit() error {
+	acks := make(chan error, len(p.shards))
+	for _, shard := range p.shards {
+		shard <- shardMessage{commit: acks}
+	}
+
+	for range p.shards {
+		<-acks
+	}
+	return p.failed()
+}
+
+// close waits for the workers to write their last batches and returns the first error any of them hit
+func (p *reportPool) close() error {
+	for _, shard := range p.shards {
+		close(shard)
+	}
+	p.wg.Wait()
+	return p.failed()
+}
+
+// total sums the results of all workers
+func (p *reportPool) total() BatchResult {
+	var total BatchResult
+	for _, batcher := range p.batchers {
+		total.Inserted += batcher.total.Inserted
+		total.Skipped += batcher.total.Skipped
+		total.Failed += batcher.total.Failed
+	}
+	return total
+}
+
+func (p *reportPool) fail(err error) {
+	p.errMu.Lock()
+	defer p.errMu.Unlock()
+
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+func (p *reportPool) failed() error {
+	p.errMu.Lock()
+	defer p.errMu.Unlock()
+
+	return p.err
+}
+
+func shardOf(appID string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(appID))
+	return int(h.Sum32() % uint32(shards))
+}
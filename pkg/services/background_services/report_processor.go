@@ -3,125 +3,158 @@ package background_services
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 
 	"github.com/trsnaqe/protondb-api/pkg/models"
-	"github.com/trsnaqe/protondb-api/pkg/services/games_service"
-	"github.com/trsnaqe/protondb-api/pkg/services/reports_service"
-	"github.com/trsnaqe/protondb-api/pkg/storage"
 )
 
-func processReports(reports interface{}) error {
-	switch reports := reports.(type) {
-	case []models.ReportFormatV1:
-		for _, report := range reports {
-			err := processReport(report, report.AppID, report.Title, "V1")
-			if err != nil {
-				return err
-			}
-		}
-	case []models.ReportFormatV2:
-		for _, report := range reports {
-			err := processReport(report, fmt.Sprint(report.App.Steam.AppID), report.App.Title, "V2")
-			if err != nil {
-				return err
-			}
-		}
-	default:
-		return fmt.Errorf("invalid report type")
-	}
-	return nil
+// normalizer is implemented by both report formats
+type normalizer interface {
+	Normalize(appID string) models.NormalizedReport
 }
 
-func processReport(report interface{}, appID, title, version string) error {
+// newPendingReport prepares a decoded report for insertion
+func newPendingReport(report normalizer, appID, title, version string) (pendingReport, error) {
 	reportMap := make(map[string]interface{})
 	j, _ := json.Marshal(report)
 	json.Unmarshal(j, &reportMap)
 
-	if isScientificNotation(appID) {
-		appIDInt, err := convertScientificNotation(appID)
-		if err != nil {
-			return err
-		}
-		appID = fmt.Sprint(appIDInt)
-	}
-	game, err := games_service.GetOrCreateGame(appID, &title)
+	appID, err := models.NormalizeAppID(appID)
 	if err != nil {
-		return err
+		return pendingReport{}, err
 	}
 
-	err = games_service.UpdateGameTitle(game, &title)
-	if err != nil {
-		return err
-	}
+	return pendingReport{appID: appID, title: title, report: models.NewReport(appID, reportMap, version, report.Normalize(appID))}, nil
+}
 
-	// If report is of V2 type, compare it before insertion
-	if version == "V2" {
-		v2report, ok := report.(models.ReportFormatV2)
-		if ok {
-			if storage.CompareReport(v2report) {
-				log.Println("Report already exists in the database. Skipping...")
-				return nil
-			}
-		} else {
-			return fmt.Errorf("error casting report to V2 type")
-		}
-	}
+// ProcessReportFile decodes the JSON array of reports read from r one element at a time,
+// detecting the format of each report, so memory use does not grow with the size of the dump.
+// Decoded reports are handed to a pool of workers which write them in batches.
+//
+// The first resumeFrom reports are skipped, which lets an interrupted run continue where it stopped.
+// Periodically all pending batches are written and checkpoint is called with the index of the last
+// report now in the database.
+func ProcessReportFile(file string, r io.Reader, resumeFrom int64, checkpoint func(lastCommittedIndex int64) error) error {
+	log.Println("Starting to process report file...")
+
+	workers := GetWorkerCount()
+	progress.start(file, workers)
+	defer progress.finish()
 
-	err = reports_service.CreateNewReport(reportMap, game, version)
+	decoder := json.NewDecoder(r)
+
+	token, err := decoder.Token()
 	if err != nil {
+		log.Println("Error while reading the start of the report array:", err)
 		return err
 	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected a JSON array of reports, got %v", token)
+	}
 
-	return nil
-}
+	if resumeFrom > 0 {
+		log.Printf("Resuming after report %d...", resumeFrom-1)
+	}
 
-func ProcessReportFile(file []byte) error {
-	log.Println("Starting to process report file...")
+	pool := newReportPool(workers, GetBatchSize())
+	checkpointInterval := int64(workers * GetBatchSize())
+	var index int64
+	for ; decoder.More(); index++ {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			log.Printf("Error while decoding report %d: %v", index, err)
+			pool.close()
+			return err
+		}
 
-	var v1Reports []models.ReportFormatV1
-	var v2Reports []models.ReportFormatV2
+		if index < resumeFrom {
+			continue
+		}
 
-	err := json.Unmarshal(file, &v2Reports)
-	if err != nil {
-		log.Println("Error while unmarshalling JSON into ReportFormatV2:", err)
-		err = json.Unmarshal(file, &v1Reports)
+		report, err := decodeRawReport(raw)
 		if err != nil {
-			log.Println("Error while unmarshalling JSON into ReportFormatV1:", err)
+			log.Printf("Error while processing report %d: %v", index, err)
+			pool.close()
 			return err
 		}
-		err = processReports(v1Reports)
-	} else {
-		// Check if there is any existing V2 data in the database
-		v2Count, err := storage.CountV2Reports()
-		if err != nil {
-			log.Println("Error while checking V2 report count in the database:", err)
+
+		if err := pool.submit(report); err != nil {
+			pool.close()
 			return err
 		}
 
-		if v2Count > 0 {
-			// Process V2 reports in reverse
-			log.Println("Processing V2 reports in reverse...")
-			for i := len(v2Reports) - 1; i >= 0; i-- {
-				err = processReports(v2Reports[i : i+1]) // Process one report at a time
-				if err != nil {
-					return err
-				}
+		progress.addDecoded()
+		if (index+1)%checkpointInterval == 0 {
+			if err := pool.commit(); err != nil {
+				pool.close()
+				return err
 			}
-		} else {
-			// Process V2 reports in normal order
-			log.Println("Processing V2 reports in normal...")
-			err = processReports(v2Reports)
-			if err != nil {
+			if err := checkpoint(index); err != nil {
+				log.Println("Error while saving checkpoint:", err)
+				pool.close()
 				return err
 			}
 		}
+		if (index+1)%10000 == 0 {
+			log.Printf("Processed %d reports...", index+1)
+		}
 	}
 
-	if err != nil {
+	if _, err := decoder.Token(); err != nil {
+		log.Println("Error while reading the end of the report array:", err)
+		pool.close()
 		return err
 	}
 
-	log.Println("Finished processing report file.")
+	if err := pool.close(); err != nil {
+		return err
+	}
+
+	total := pool.total()
+	log.Printf("Finished processing report file, %d reports: %d inserted, %d skipped, %d failed.",
+		index, total.Inserted, total.Skipped, total.Failed)
 	return nil
 }
+
+// decodeRawReport decodes a single report in the format it was written in
+func decodeRawReport(raw json.RawMessage) (pendingReport, error) {
+	version, err := detectReportVersion(raw)
+	if err != nil {
+		return pendingReport{}, err
+	}
+
+	switch version {
+	case "V2":
+		var report models.ReportFormatV2
+		if err := json.Unmarshal(raw, &report); err != nil {
+			return pendingReport{}, err
+		}
+		return newPendingReport(report, fmt.Sprint(report.App.Steam.AppID), report.App.Title, "V2")
+	default:
+		var report models.ReportFormatV1
+		if err := json.Unmarshal(raw, &report); err != nil {
+			return pendingReport{}, err
+		}
+		return newPendingReport(report, report.AppID, report.Title, "V1")
+	}
+}
+
+// detectReportVersion tells V2 reports, which nest the app under "app", from flat V1 reports
+func detectReportVersion(raw json.RawMessage) (string, error) {
+	var probe struct {
+		App   json.RawMessage `json:"app"`
+		AppID json.RawMessage `json:"appId"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return "", err
+	}
+
+	switch {
+	case len(probe.App) > 0 && string(probe.App) != "null":
+		return "V2", nil
+	case len(probe.AppID) > 0 && string(probe.AppID) != "null":
+		return "V1", nil
+	}
+	return "", fmt.Errorf("unknown report format")
+}
@@ -0,0 +1,185 @@
+package background_services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/github"
+)
+
+// ReportSource lists and opens the ProtonDB data dumps
+type ReportSource interface {
+	// ListFiles returns the names of the available dumps, oldest first
+	ListFiles() ([]string, error)
+	// Open returns the content of a dump listed by ListFiles, either a .tar.gz archive or a JSON file
+	Open(file string) (io.ReadCloser, error)
+	// Checksum returns a value that changes whenever the content of the dump changes
+	Checksum(file string) (string, error)
+}
+
+// GitHubSource reads the dumps published in the bdefore/protondb-data repository
+type GitHubSource struct {
+	mu   sync.Mutex
+	shas map[string]string
+}
+
+func NewGitHubSource() *GitHubSource {
+	return &GitHubSource{}
+}
+
+func (s *GitHubSource) ListFiles() ([]string, error) {
+	client := github.NewClient(nil)
+	ctx := context.Background()
+
+	tree, _, err := client.Git.GetTree(ctx, "bdefore", "protondb-data", "master", true)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileList []string
+	shas := make(map[string]string)
+	for _, entry := range tree.Entries {
+		if entry.GetType() == "blob" && strings.HasPrefix(entry.GetPath(), "reports/") && strings.HasSuffix(entry.GetPath(), ".tar.gz") {
+			fileList = append(fileList, entry.GetPath())
+			shas[entry.GetPath()] = entry.GetSHA()
+		}
+	}
+
+	s.mu.Lock()
+	s.shas = shas
+	s.mu.Unlock()
+
+	sortFiles(fileList)
+	return fileList, nil
+}
+
+// Checksum returns the git blob SHA of the dump, so it does not need to be downloaded first
+func (s *GitHubSource) Checksum(file string) (string, error) {
+	s.mu.Lock()
+	sha, ok := s.shas[file]
+	s.mu.Unlock()
+	if ok {
+		return sha, nil
+	}
+
+	if _, err := s.ListFiles(); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sha, ok := s.shas[file]; ok {
+		return sha, nil
+	}
+	return "", fmt.Errorf("file not found: %s", file)
+}
+
+func (s *GitHubSource) Open(file string) (io.ReadCloser, error) {
+	fileURL := fmt.Sprintf("%s/%s", rawBaseURL, file)
+	resp, err := http.Get(fileURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download file. Status code: %d", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
+
+// LocalSource reads dumps from a directory on disk. The directory may hold reports_*.tar.gz archives,
+// already extracted reports_*.json files or reports_* directories containing the extracted JSON file.
+type LocalSource struct {
+	Dir string
+}
+
+func NewLocalSource(dir string) *LocalSource {
+	return &LocalSource{Dir: dir}
+}
+
+func (s *LocalSource) ListFiles() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileList []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "reports_") {
+			continue
+		}
+		if !entry.IsDir() && !strings.HasSuffix(name, ".tar.gz") && !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		if _, err := dateFromFile(name); err != nil {
+			continue
+		}
+		fileList = append(fileList, name)
+	}
+
+	sortFiles(fileList)
+	return fileList, nil
+}
+
+func (s *LocalSource) Open(file string) (io.ReadCloser, error) {
+	path := filepath.Join(s.Dir, file)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		path, err = findJSONFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return os.Open(path)
+}
+
+// Checksum returns the SHA-256 of the dump's archive or JSON file
+func (s *LocalSource) Checksum(file string) (string, error) {
+	reader, err := s.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func sortFiles(fileList []string) {
+	sort.Slice(fileList, func(i, j int) bool {
+		return compareFiles(fileList[i], fileList[j])
+	})
+}
+
+var reportSource ReportSource = NewGitHubSource()
+
+// SetReportSource sets the source the background process reads dumps from
+func SetReportSource(source ReportSource) {
+	reportSource = source
+}
+
+// GetReportSource returns the source the background process reads dumps from
+func GetReportSource() ReportSource {
+	return reportSource
+}
@@ -1,152 +1,187 @@
 package games_service
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
 
 	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/pagination"
+	"github.com/trsnaqe/protondb-api/pkg/search"
 	"github.com/trsnaqe/protondb-api/pkg/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func GetAllGames() ([]models.Game, error) {
-	cursor, err := storage.GetAllGames()
+// ListGames returns a page of games and the cursor of the next page, empty on the last page
+func ListGames(page pagination.Params) ([]models.Game, string, error) {
+	// Ask for one more game to know whether there is a next page
+	games, err := storage.GetStore().ListGames(page.After(), page.Limit+1)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	defer cursor.Close(nil)
 
-	var games []models.Game
-	for cursor.Next(nil) {
-		var game models.Game
-		if err := cursor.Decode(&game); err != nil {
-			return nil, err
+	var next string
+	if len(games) > page.Limit {
+		games = games[:page.Limit]
+		next = pagination.Cursor{ID: games[page.Limit-1].ID}.Encode()
+	}
+	return games, next, nil
+}
+
+// RebuildSearchIndex builds the title search index from the stored games and swaps it in
+func RebuildSearchIndex() error {
+	games, err := storage.GetStore().GetAllGames()
+	if err != nil {
+		return err
+	}
+	reportCounts, err := storage.GetStore().GetReportCounts()
+	if err != nil {
+		return err
+	}
+
+	entries := make([]search.Entry, 0, len(games))
+	for _, game := range games {
+		if game.Title == nil {
+			continue
+		}
+		entry := search.Entry{ID: game.ID, AppID: game.AppID, Title: *game.Title, Reports: reportCounts[game.AppID]}
+		for _, alias := range game.Titles {
+			entry.Aliases = append(entry.Aliases, alias.Title)
 		}
-		games = append(games, game)
+		entries = append(entries, entry)
 	}
 
-	return games, nil
+	search.SetIndex(search.NewIndex(entries))
+	log.Printf("Search index built with %d games", len(entries))
+	return nil
 }
 
+// SearchGames returns the games matching the title in the search index, best first
+func SearchGames(title string, precision float64) []search.Match {
+	return search.GetIndex().Search(title, precision)
+}
+
+// SuggestGames returns up to limit games whose title completes the query, from the search index
+func SuggestGames(query string, limit int) []search.Suggestion {
+	return search.GetIndex().Suggest(query, limit)
+}
+
+// SearchGameByTitle returns the stored games matching the title, best first
 func SearchGameByTitle(title string, precision float64) ([]models.Game, error) {
-	cursor, err := storage.SearchGameByTitle(title, precision)
+	matches := SearchGames(title, precision)
+	if len(matches) == 0 {
+		return []models.Game{}, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(matches))
+	for _, match := range matches {
+		ids = append(ids, match.ID)
+	}
+	stored, err := storage.GetStore().GetGamesByIDs(ids)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(nil)
 
-	var games []models.Game
-	for cursor.Next(nil) {
-		var game models.Game
-		if err := cursor.Decode(&game); err != nil {
-			return nil, err
+	byID := make(map[primitive.ObjectID]models.Game, len(stored))
+	for _, game := range stored {
+		byID[game.ID] = game
+	}
+	// Games deleted since the index was built are left out
+	games := make([]models.Game, 0, len(stored))
+	for _, id := range ids {
+		if game, ok := byID[id]; ok {
+			games = append(games, game)
 		}
-		games = append(games, game)
 	}
-
 	return games, nil
 }
 
 func GetGameByAppID(gameID string) (*models.Game, error) {
-	game, err := storage.GetGameByAppID(gameID)
+	game, err := storage.GetStore().GetGameByAppID(gameID)
 	if err != nil {
 		return nil, err
 	}
 	return game, nil
 }
 
-func GetGameByQuery(appId string, title string, precision float64) ([]models.Game, error) {
-	ctx := context.TODO()
+// ErrGameNotFound is returned when no game has the requested appId
+var ErrGameNotFound = errors.New("no game found")
 
+func GetGameByQuery(appId string, title string, precision float64) ([]models.Game, error) {
 	if appId != "" {
 		appId = strings.ToLower(appId)
-		game, err := storage.GetGameByAppID(appId)
-		if err == nil {
-			return []models.Game{*game}, nil
-		}
-		return nil, fmt.Errorf("no game found with appId: %s", appId)
-	}
-
-	if title != "" {
-		cursor, err := storage.SearchGameByTitle(title, precision)
+		game, err := storage.GetStore().GetGameByAppID(appId)
 		if err != nil {
 			return nil, err
 		}
-		defer cursor.Close(ctx)
-
-		var games []models.Game
-		for cursor.Next(ctx) {
-			var game models.Game
-			if err := cursor.Decode(&game); err != nil {
-				return nil, err
-			}
-			games = append(games, game)
-		}
-		if err := cursor.Err(); err != nil {
-			return nil, err
+		if game == nil {
+			return nil, fmt.Errorf("%w with appId: %s", ErrGameNotFound, appId)
 		}
-		return games, nil
+		return []models.Game{*game}, nil
+	}
+
+	if title != "" {
+		return SearchGameByTitle(title, precision)
 	}
 
 	return nil, fmt.Errorf("no valid query parameters provided")
 }
-func AddReportToGame(game *models.Game, report *models.Report) error {
-	return storage.InsertReport(game, report.ID)
-}
 
+// GetGameSummary computes the summary of a game from its stored reports, see ComputeGameSummary.
+// It returns nil if there is no game with the app ID.
 func GetGameSummary(appID string) (*models.GameSummary, error) {
-	apiURL := fmt.Sprintf("https://www.protondb.com/api/v1/reports/summaries/%s.json", appID)
-	resp, err := http.Get(apiURL)
+	game, err := storage.GetStore().GetGameByAppID(appID)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch game summary. Status code: %d", resp.StatusCode)
+	if game == nil {
+		return nil, nil
 	}
 
-	var summary models.GameSummary
-	err = json.NewDecoder(resp.Body).Decode(&summary)
+	ratings, err := storage.GetStore().GetReportRatings(appID)
 	if err != nil {
 		return nil, err
 	}
 
+	summary := ComputeGameSummary(ratings)
 	return &summary, nil
 }
 
-func GetOrCreateGame(appID string, title *string) (*models.Game, error) {
-	game, err := storage.GetGameByAppID(appID)
+// GetGameStats breaks the stored reports of a game down, see models.GameStats. Distributions of free-form
+// values are cut to their top most common values. It returns nil if there is no game with the app ID.
+func GetGameStats(appID string, top int) (*models.GameStats, error) {
+	game, err := storage.GetStore().GetGameByAppID(appID)
 	if err != nil {
-		log.Println("Error getting game by app ID:", err)
 		return nil, err
 	}
-
 	if game == nil {
-		log.Println("No existing game found. Creating a new one...")
-		game = models.NewGame(appID, title)
-		err = storage.CreateGame(game)
-		if err != nil {
-			log.Println("Error creating game:", err)
-			return nil, err
-		}
+		return nil, nil
 	}
 
-	return game, nil
+	return storage.GetStore().GetGameStats(appID, top)
 }
 
-func UpdateGameTitle(game *models.Game, title *string) error {
-	if game.Title == nil || *game.Title != *title {
-		game.Title = title
+// GetProtonDBGameSummary fetches the summary protondb.com serves for a game, to compare against ours
+func GetProtonDBGameSummary(appID string) (*models.GameSummary, error) {
+	apiURL := fmt.Sprintf("https://www.protondb.com/api/v1/reports/summaries/%s.json", appID)
+	resp, err := http.Get(apiURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-		err := storage.ChangeTitle(game.ID, *title)
-		if err != nil {
-			log.Println("Error updating game:", err)
-			return err
-		}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch game summary. Status code: %d", resp.StatusCode)
 	}
-	return nil
+
+	var summary models.GameSummary
+	err = json.NewDecoder(resp.Body).Decode(&summary)
+	if err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
 }
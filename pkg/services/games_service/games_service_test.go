@@ -0,0 +1,25 @@
+package games_service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/trsnaqe/protondb-api/pkg/storage"
+)
+
+func TestGetGameByQuery(t *testing.T) {
+	storage.SetStore(storage.NewMemoryStore())
+	if _, err := storage.GetStore().UpsertGames(map[string]string{"10": "Hades"}); err != nil {
+		t.Fatal(err)
+	}
+
+	games, err := GetGameByQuery("10", "", 0)
+	if err != nil || len(games) != 1 || games[0].AppID != "10" {
+		t.Errorf("GetGameByQuery(10) = %v, %v, want Hades", games, err)
+	}
+
+	games, err = GetGameByQuery("20", "", 0)
+	if !errors.Is(err, ErrGameNotFound) {
+		t.Errorf("GetGameByQuery of a missing game = %v, %v, want ErrGameNotFound", games, err)
+	}
+}
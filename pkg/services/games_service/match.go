@@ -0,0 +1,269 @@
+package games_service
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/trsnaqe/protondb-api/pkg/hardware"
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/search"
+	"github.com/trsnaqe/protondb-api/pkg/storage"
+)
+
+// A setup is matched against the normalized system info of the reports of a game:
+//
+//   - Each given field of the setup is compared to the report's and scores 0..1: GPUs by vendor and
+//     model words, drivers by words and version, kernels by version, distros by words, Proton by version.
+//   - The similarity of a report is the weighted mean of those scores over the fields given,
+//     a report missing a field scores 0 on it.
+//   - The predicted rating is the mean of the ratings points of the most similar reports, at most
+//     predictionReports of those with a similarity of at least minMatchSimilarity, weighted by the
+//     square of their similarity. It is scaled and mapped to a tier the way the game summary is.
+
+const (
+	// matchSampleReports is how many of the most recent reports of a game are compared to the setup
+	matchSampleReports = 5000
+	predictionReports  = 50
+	minMatchSimilarity = 0.5
+)
+
+// Weights of the fields in the similarity, the GPU and its driver matter most for how a game runs
+var matchWeights = struct {
+	gpu, driver, proton, kernel, distro float64
+}{gpu: 3, driver: 2, proton: 2, kernel: 1, distro: 1}
+
+// Setup is the hardware and software to find similar reports for. Empty fields are ignored.
+type Setup struct {
+	GPU           string
+	GPUDriver     string
+	Kernel        string
+	Distro        string
+	ProtonVersion string
+}
+
+func (s Setup) IsEmpty() bool {
+	return s.GPU == "" && s.GPUDriver == "" && s.Kernel == "" && s.Distro == "" && s.ProtonVersion == ""
+}
+
+// SimilarReport is a report of the game along with how similar its setup is to the one matched
+type SimilarReport struct {
+	Similarity float64                 `json:"similarity"`
+	Report     models.NormalizedReport `json:"report"`
+}
+
+// RatingPrediction is the rating expected for a setup, from the reports of the most similar setups
+type RatingPrediction struct {
+	Rating string  `json:"rating"`
+	Score  float64 `json:"score"`
+	// BasedOn is the number of reports the prediction was made from
+	BasedOn    int    `json:"basedOn"`
+	Confidence string `json:"confidence"`
+}
+
+// SetupMatch is the outcome of matching a setup against the reports of a game
+type SetupMatch struct {
+	AppID      string           `json:"appId"`
+	Prediction RatingPrediction `json:"prediction"`
+	Reports    []SimilarReport  `json:"reports"`
+}
+
+// MatchSetup ranks the recent reports of a game by how similar their setup is to the given one and
+// predicts the rating of the game on it. It returns up to limit reports, and nil if there is no game
+// with the app ID.
+func MatchSetup(appID string, setup Setup, limit int) (*SetupMatch, error) {
+	game, err := storage.GetStore().GetGameByAppID(appID)
+	if err != nil {
+		return nil, err
+	}
+	if game == nil {
+		return nil, nil
+	}
+
+	reports, err := storage.GetStore().ListReports(storage.ReportQuery{
+		AppIDs: []string{appID},
+		Sort:   storage.ReportSort{Field: "timestamp", Desc: true},
+		Fields: []string{"normalized"},
+		Limit:  matchSampleReports,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	similar := make([]SimilarReport, 0, len(reports))
+	for _, report := range reports {
+		if report.Normalized == nil {
+			continue
+		}
+		similar = append(similar, SimilarReport{
+			Similarity: math.Round(setupSimilarity(setup, *report.Normalized)*1000) / 1000,
+			Report:     *report.Normalized,
+		})
+	}
+	// Most similar first, then most recent
+	sort.SliceStable(similar, func(i, j int) bool {
+		return similar[i].Similarity > similar[j].Similarity
+	})
+
+	match := &SetupMatch{AppID: appID, Prediction: predictRating(similar)}
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+	match.Reports = similar
+	return match, nil
+}
+
+// predictRating predicts a rating from reports sorted by descending similarity
+func predictRating(similar []SimilarReport) RatingPrediction {
+	var weightedPoints, totalWeight float64
+	basedOn := 0
+	for _, report := range similar {
+		if basedOn == predictionReports || report.Similarity < minMatchSimilarity {
+			break
+		}
+		points, ok := ratingPoints(report.Report.Rating)
+		if !ok {
+			continue
+		}
+		weight := report.Similarity * report.Similarity
+		weightedPoints += weight * float64(points)
+		totalWeight += weight
+		basedOn++
+	}
+
+	if basedOn == 0 {
+		return RatingPrediction{Rating: "pending", Confidence: confidenceForTotal(0)}
+	}
+
+	score := weightedPoints / totalWeight / float64(len(tiers)-1)
+	return RatingPrediction{
+		Rating:     tierForScore(score),
+		Score:      math.Round(score*100) / 100,
+		BasedOn:    basedOn,
+		Confidence: confidenceForTotal(basedOn),
+	}
+}
+
+// setupSimilarity scores how similar the setup of a report is to the given one, from 0 to 1
+func setupSimilarity(setup Setup, report models.NormalizedReport) float64 {
+	var score, weights float64
+	add := func(weight float64, given string, similarity func() float64) {
+		if given == "" {
+			return
+		}
+		weights += weight
+		score += weight * similarity()
+	}
+
+	add(matchWeights.gpu, setup.GPU, func() float64 { return gpuSimilarity(setup.GPU, report.GPU) })
+	add(matchWeights.driver, setup.GPUDriver, func() float64 {
+		return (wordSimilarity(setup.GPUDriver, report.GPUDriver) + versionSimilarity(setup.GPUDriver, report.GPUDriver)) / 2
+	})
+	add(matchWeights.proton, setup.ProtonVersion, func() float64 {
+		if strings.EqualFold(strings.TrimSpace(setup.ProtonVersion), strings.TrimSpace(report.ProtonVersion)) {
+			return 1
+		}
+		return versionSimilarity(setup.ProtonVersion, report.ProtonVersion)
+	})
+	add(matchWeights.kernel, setup.Kernel, func() float64 { return kernelSimilarity(setup.Kernel, report.Kernel) })
+	add(matchWeights.distro, setup.Distro, func() float64 { return wordSimilarity(setup.Distro, report.OS) })
+
+	if weights == 0 {
+		return 0
+	}
+	return score / weights
+}
+
+// gpuSimilarity scores two GPUs half on their vendor and half on the words of their names
+func gpuSimilarity(a, b string) float64 {
+	if b == "" {
+		return 0
+	}
+	vendorA, vendorB := hardware.GPUVendor(a), hardware.GPUVendor(b)
+	if vendorA == hardware.VendorUnknown {
+		return wordSimilarity(a, b)
+	}
+	vendor := 0.0
+	if vendorA == vendorB {
+		vendor = 1
+	}
+	return (vendor + wordSimilarity(a, b)) / 2
+}
+
+// wordSimilarity is the share of the words of a and b they have in common
+func wordSimilarity(a, b string) float64 {
+	wordsA, wordsB := search.Tokenize(a), search.Tokenize(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+
+	inA := make(map[string]bool, len(wordsA))
+	for _, word := range wordsA {
+		inA[word] = true
+	}
+	common := 0
+	for _, word := range wordsB {
+		if inA[word] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(wordsA)+len(wordsB)-common)
+}
+
+var versionPattern = regexp.MustCompile(`\d+(?:\.\d+)*`)
+
+// versionSimilarity compares the first versions found in a and b: 1 if equal, 0.8 if only later parts
+// differ from the minor version on, 0.5 if only the major version is the same, 0 otherwise
+func versionSimilarity(a, b string) float64 {
+	versionA, versionB := versionParts(a), versionParts(b)
+	if len(versionA) == 0 || len(versionB) == 0 || versionA[0] != versionB[0] {
+		return 0
+	}
+	if len(versionA) != len(versionB) {
+		if len(versionA) > 1 && len(versionB) > 1 && versionA[1] == versionB[1] {
+			return 0.8
+		}
+		return 0.5
+	}
+	for i := range versionA {
+		if versionA[i] != versionB[i] {
+			if i >= 2 {
+				return 0.8
+			}
+			return 0.5
+		}
+	}
+	return 1
+}
+
+func versionParts(value string) []int {
+	match := versionPattern.FindString(value)
+	if match == "" {
+		return nil
+	}
+	var parts []int
+	for _, part := range strings.Split(match, ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return nil
+		}
+		parts = append(parts, number)
+	}
+	return parts
+}
+
+// kernelSimilarity scores kernels 1 on the same minor version, 0.5 on the same major version
+func kernelSimilarity(a, b string) float64 {
+	versionA, versionB := hardware.VersionCode(a), hardware.VersionCode(b)
+	switch {
+	case versionA == 0 || versionB == 0:
+		return 0
+	case versionA/1000 == versionB/1000:
+		return 1
+	case versionA/1000000 == versionB/1000000:
+		return 0.5
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+package games_service
+
+import (
+	"math"
+	"sort"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/proton"
+	"github.com/trsnaqe/protondb-api/pkg/storage"
+)
+
+const (
+	// minReleaseReports is the number of reports a release needs to tell whether the game works on it
+	minReleaseReports = 3
+	// minWorkingShare is the share of reports rated silver or better for the game to work on a release
+	minWorkingShare = 0.5
+)
+
+// GetProtonCompatibility breaks the reports of a game down per Proton build and release, and finds for each
+// build the release the game works since: the oldest of the latest releases it works on, skipping releases
+// with too few reports to tell. It returns nil if there is no game with the app ID.
+func GetProtonCompatibility(appID string) (*models.ProtonCompatibility, error) {
+	game, err := storage.GetStore().GetGameByAppID(appID)
+	if err != nil {
+		return nil, err
+	}
+	if game == nil {
+		return nil, nil
+	}
+
+	counts, err := storage.GetStore().GetProtonReleaseCounts(appID)
+	if err != nil {
+		return nil, err
+	}
+
+	byBuild := make(map[string][]models.ProtonReleaseCount)
+	for _, count := range counts {
+		byBuild[count.Build] = append(byBuild[count.Build], count)
+	}
+
+	compatibility := &models.ProtonCompatibility{AppID: appID, Builds: []models.ProtonBuildCompatibility{}}
+	for _, build := range proton.Builds {
+		releases := byBuild[build]
+		if len(releases) == 0 {
+			continue
+		}
+		sort.Slice(releases, func(i, j int) bool {
+			if releases[i].Major != releases[j].Major {
+				return releases[i].Major < releases[j].Major
+			}
+			return releases[i].Minor < releases[j].Minor
+		})
+		compatibility.Builds = append(compatibility.Builds, buildCompatibility(build, releases))
+	}
+	return compatibility, nil
+}
+
+// buildCompatibility summarizes the releases of a build, sorted from the oldest
+func buildCompatibility(build string, releases []models.ProtonReleaseCount) models.ProtonBuildCompatibility {
+	compatibility := models.ProtonBuildCompatibility{Build: build}
+	for _, release := range releases {
+		compatibility.Releases = append(compatibility.Releases, models.ProtonRelease{
+			Version:      proton.Series(build, release.Major, release.Minor),
+			Reports:      release.Reports,
+			Working:      release.Working,
+			WorkingShare: math.Round(float64(release.Working)/float64(release.Reports)*100) / 100,
+		})
+	}
+
+	// Walk back from the latest release while the game works, releases without a version have no place in the order
+	for i := len(releases) - 1; i >= 0; i-- {
+		release := releases[i]
+		if (release.Major == 0 && release.Minor == 0) || release.Reports < minReleaseReports {
+			continue
+		}
+		if float64(release.Working)/float64(release.Reports) < minWorkingShare {
+			break
+		}
+		compatibility.WorksSince = compatibility.Releases[i].Version
+	}
+	return compatibility
+}
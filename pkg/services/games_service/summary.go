@@ -0,0 +1,120 @@
+package games_service
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+)
+
+// The summary of a game is computed from the normalized ratings of its stored reports:
+//
+//   - Each rating is worth points: borked 0, bronze 1, silver 2, gold 3, platinum 4 (native counts as platinum).
+//   - Reports lose weight with age, halving every year relative to the newest report of the game,
+//     so the summary follows the current state of the game rather than its history.
+//   - score is the weighted mean of the points scaled to 0..1, and tier the rating nearest to it.
+//   - bestReportedTier is the best rating any report gave.
+//   - trendingTier is the tier of the trendingReports most recent reports, unweighted.
+//   - confidence grows with the number of reports: inadequate below 3, then weak, moderate from 10,
+//     good from 20 and strong from 40.
+//   - total is the number of rated reports. Games without any are "pending".
+
+const (
+	ratingHalfLife  = 365 * 24 * time.Hour
+	trendingReports = 20
+)
+
+var tiers = []string{models.RatingBorked, models.RatingBronze, models.RatingSilver, models.RatingGold, models.RatingPlatinum}
+
+func ratingPoints(rating string) (int, bool) {
+	if rating == models.RatingNative {
+		rating = models.RatingPlatinum
+	}
+	for points, tier := range tiers {
+		if tier == rating {
+			return points, true
+		}
+	}
+	return 0, false
+}
+
+// tierForScore returns the tier nearest to a 0..1 score
+func tierForScore(score float64) string {
+	return tiers[int(math.Round(score*float64(len(tiers)-1)))]
+}
+
+func confidenceForTotal(total int) string {
+	switch {
+	case total >= 40:
+		return "strong"
+	case total >= 20:
+		return "good"
+	case total >= 10:
+		return "moderate"
+	case total >= 3:
+		return "weak"
+	default:
+		return "inadequate"
+	}
+}
+
+// ComputeGameSummary computes the summary of a game from the ratings of its reports
+func ComputeGameSummary(ratings []models.ReportRating) models.GameSummary {
+	var rated []models.ReportRating
+	for _, rating := range ratings {
+		if _, ok := ratingPoints(rating.Rating); ok {
+			rated = append(rated, rating)
+		}
+	}
+
+	if len(rated) == 0 {
+		return models.GameSummary{
+			BestReportedTier: "pending",
+			Confidence:       confidenceForTotal(0),
+			Tier:             "pending",
+			TrendingTier:     "pending",
+		}
+	}
+
+	// Newest first
+	sort.SliceStable(rated, func(i, j int) bool {
+		return rated[i].Timestamp.After(rated[j].Timestamp)
+	})
+	newest := rated[0].Timestamp
+	maxPoints := float64(len(tiers) - 1)
+
+	var weightedPoints, totalWeight, trendingPoints float64
+	best := 0
+	for i, rating := range rated {
+		points, _ := ratingPoints(rating.Rating)
+
+		age := newest.Sub(rating.Timestamp)
+		weight := math.Pow(0.5, float64(age)/float64(ratingHalfLife))
+		weightedPoints += weight * float64(points)
+		totalWeight += weight
+
+		if points > best {
+			best = points
+		}
+		if i < trendingReports {
+			trendingPoints += float64(points)
+		}
+	}
+
+	trendingCount := len(rated)
+	if trendingCount > trendingReports {
+		trendingCount = trendingReports
+	}
+
+	score := weightedPoints / totalWeight / maxPoints
+
+	return models.GameSummary{
+		BestReportedTier: tiers[best],
+		Confidence:       confidenceForTotal(len(rated)),
+		Score:            math.Round(score*100) / 100,
+		Tier:             tierForScore(score),
+		Total:            len(rated),
+		TrendingTier:     tierForScore(trendingPoints / float64(trendingCount) / maxPoints),
+	}
+}
@@ -0,0 +1,103 @@
+package games_service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+)
+
+// ratingsOf returns count ratings written days after the first of January 2023
+func ratingsOf(rating string, count int, days int) []models.ReportRating {
+	ratings := make([]models.ReportRating, count)
+	for i := range ratings {
+		ratings[i] = models.ReportRating{Rating: rating, Timestamp: time.Date(2023, 1, 1+days, 0, 0, 0, 0, time.UTC)}
+	}
+	return ratings
+}
+
+func TestComputeGameSummary(t *testing.T) {
+	pending := models.GameSummary{BestReportedTier: "pending", Confidence: "inadequate", Tier: "pending", TrendingTier: "pending"}
+	tests := []struct {
+		name    string
+		ratings []models.ReportRating
+		want    models.GameSummary
+	}{
+		{"no reports", nil, pending},
+		{"no rated reports", append(ratingsOf("", 2, 0), ratingsOf("unknown", 1, 0)...), pending},
+		{
+			"single report",
+			ratingsOf(models.RatingGold, 1, 0),
+			models.GameSummary{BestReportedTier: "gold", Confidence: "inadequate", Score: 0.75, Tier: "gold", Total: 1, TrendingTier: "gold"},
+		},
+		{
+			"native counts as platinum",
+			ratingsOf(models.RatingNative, 3, 0),
+			models.GameSummary{BestReportedTier: "platinum", Confidence: "weak", Score: 1, Tier: "platinum", Total: 3, TrendingTier: "platinum"},
+		},
+		{
+			// The borked report weighs half as much a year older, the trending tier is unweighted
+			"older reports weigh less",
+			append(ratingsOf(models.RatingBorked, 1, 0), ratingsOf(models.RatingPlatinum, 1, 365)...),
+			models.GameSummary{BestReportedTier: "platinum", Confidence: "inadequate", Score: 0.67, Tier: "gold", Total: 2, TrendingTier: "silver"},
+		},
+		{
+			"trending on the newest reports",
+			append(ratingsOf(models.RatingBorked, 20, 0), ratingsOf(models.RatingPlatinum, 20, 1)...),
+			models.GameSummary{BestReportedTier: "platinum", Confidence: "strong", Score: 0.5, Tier: "silver", Total: 40, TrendingTier: "platinum"},
+		},
+		{
+			"unrated reports are left out",
+			append(ratingsOf(models.RatingBronze, 10, 0), ratingsOf("", 5, 0)...),
+			models.GameSummary{BestReportedTier: "bronze", Confidence: "moderate", Score: 0.25, Tier: "bronze", Total: 10, TrendingTier: "bronze"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ComputeGameSummary(test.ratings); got != test.want {
+				t.Errorf("ComputeGameSummary = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestConfidenceForTotal(t *testing.T) {
+	tests := []struct {
+		total int
+		want  string
+	}{
+		{0, "inadequate"},
+		{2, "inadequate"},
+		{3, "weak"},
+		{9, "weak"},
+		{10, "moderate"},
+		{20, "good"},
+		{39, "good"},
+		{40, "strong"},
+	}
+	for _, test := range tests {
+		if got := confidenceForTotal(test.total); got != test.want {
+			t.Errorf("confidenceForTotal(%d) = %q, want %q", test.total, got, test.want)
+		}
+	}
+}
+
+func TestTierForScore(t *testing.T) {
+	tests := []struct {
+		score float64
+		want  string
+	}{
+		{0, models.RatingBorked},
+		{0.12, models.RatingBorked},
+		{0.13, models.RatingBronze},
+		{0.5, models.RatingSilver},
+		{0.75, models.RatingGold},
+		{0.875, models.RatingPlatinum},
+		{1, models.RatingPlatinum},
+	}
+	for _, test := range tests {
+		if got := tierForScore(test.score); got != test.want {
+			t.Errorf("tierForScore(%v) = %q, want %q", test.score, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package games_service
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/storage"
+)
+
+// Lengths of the periods of a game timeline
+const (
+	BucketDay     = "day"
+	BucketWeek    = "week"
+	BucketMonth   = "month"
+	BucketQuarter = "quarter"
+	BucketYear    = "year"
+)
+
+var TimelineBuckets = []string{BucketDay, BucketWeek, BucketMonth, BucketQuarter, BucketYear}
+
+var timelineRatings = []string{models.RatingBorked, models.RatingBronze, models.RatingSilver, models.RatingGold, models.RatingPlatinum, models.RatingNative}
+
+// GetGameTimeline counts the reports of a game and their ratings per period of the bucket length, on the
+// timestamps of the normalized reports, whether they came as V1 or V2 reports. It returns nil if there is
+// no game with the app ID.
+func GetGameTimeline(appID string, bucket string) (*models.GameTimeline, error) {
+	game, err := storage.GetStore().GetGameByAppID(appID)
+	if err != nil {
+		return nil, err
+	}
+	if game == nil {
+		return nil, nil
+	}
+
+	ratings, err := storage.GetStore().GetReportRatings(appID)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := &models.GameTimeline{AppID: appID, Bucket: bucket, Buckets: []models.TimelineBucket{}}
+	byStart := make(map[time.Time][]models.ReportRating)
+	var first, last time.Time
+	for _, rating := range ratings {
+		if rating.Timestamp.IsZero() {
+			timeline.Undated++
+			continue
+		}
+		start := bucketStart(rating.Timestamp, bucket)
+		byStart[start] = append(byStart[start], rating)
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if start.After(last) {
+			last = start
+		}
+	}
+	if first.IsZero() {
+		return timeline, nil
+	}
+
+	for start := first; !start.After(last); start = nextBucket(start, bucket) {
+		timeline.Buckets = append(timeline.Buckets, timelineBucket(start, bucket, byStart[start]))
+	}
+	return timeline, nil
+}
+
+// timelineBucket counts the ratings of the reports of a period, a period without rated reports is "pending"
+func timelineBucket(start time.Time, bucket string, ratings []models.ReportRating) models.TimelineBucket {
+	result := models.TimelineBucket{
+		Period:  bucketPeriod(start, bucket),
+		Start:   start,
+		Reports: int64(len(ratings)),
+		Ratings: make(map[string]int64, len(timelineRatings)),
+		Tier:    "pending",
+	}
+	for _, rating := range timelineRatings {
+		result.Ratings[rating] = 0
+	}
+
+	var totalPoints, rated int
+	for _, rating := range ratings {
+		points, ok := ratingPoints(rating.Rating)
+		if !ok {
+			continue
+		}
+		result.Ratings[rating.Rating]++
+		totalPoints += points
+		rated++
+	}
+	if rated > 0 {
+		score := float64(totalPoints) / float64(rated) / float64(len(tiers)-1)
+		result.Score = math.Round(score*100) / 100
+		result.Tier = tierForScore(score)
+	}
+	return result
+}
+
+// bucketStart returns the start of the period a time falls in, in UTC. Weeks start on Monday.
+func bucketStart(t time.Time, bucket string) time.Time {
+	t = t.UTC()
+	year, month, day := t.Date()
+	switch bucket {
+	case BucketDay:
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	case BucketWeek:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, time.UTC)
+	case BucketQuarter:
+		return time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	case BucketYear:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// nextBucket returns the start of the period following the one starting at start
+func nextBucket(start time.Time, bucket string) time.Time {
+	switch bucket {
+	case BucketDay:
+		return start.AddDate(0, 0, 1)
+	case BucketWeek:
+		return start.AddDate(0, 0, 7)
+	case BucketQuarter:
+		return start.AddDate(0, 3, 0)
+	case BucketYear:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// bucketPeriod labels the period starting at start, weeks by their ISO week
+func bucketPeriod(start time.Time, bucket string) string {
+	switch bucket {
+	case BucketDay:
+		return start.Format("2006-01-02")
+	case BucketWeek:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case BucketQuarter:
+		return fmt.Sprintf("%d-Q%d", start.Year(), (int(start.Month())-1)/3+1)
+	case BucketYear:
+		return start.Format("2006")
+	}
+	return start.Format("2006-01")
+}
@@ -0,0 +1,143 @@
+package games_service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/storage"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestBucketStart(t *testing.T) {
+	wednesday := time.Date(2023, 2, 15, 18, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		time   time.Time
+		bucket string
+		want   time.Time
+	}{
+		{"day", wednesday, BucketDay, date(2023, 2, 15)},
+		{"week starts on Monday", wednesday, BucketWeek, date(2023, 2, 13)},
+		{"week of a Sunday", date(2023, 1, 1), BucketWeek, date(2022, 12, 26)},
+		{"week of a Monday", date(2023, 1, 2), BucketWeek, date(2023, 1, 2)},
+		{"month", wednesday, BucketMonth, date(2023, 2, 1)},
+		{"quarter", wednesday, BucketQuarter, date(2023, 1, 1)},
+		{"last quarter", date(2023, 12, 31), BucketQuarter, date(2023, 10, 1)},
+		{"year", wednesday, BucketYear, date(2023, 1, 1)},
+		{"unknown bucket is a month", wednesday, "decade", date(2023, 2, 1)},
+		{"in UTC", time.Date(2023, 3, 1, 1, 0, 0, 0, time.FixedZone("CEST", 2*60*60)), BucketMonth, date(2023, 2, 1)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := bucketStart(test.time, test.bucket); !got.Equal(test.want) {
+				t.Errorf("bucketStart(%s, %s) = %s, want %s", test.time, test.bucket, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNextBucket(t *testing.T) {
+	tests := []struct {
+		start  time.Time
+		bucket string
+		want   time.Time
+	}{
+		{date(2023, 2, 28), BucketDay, date(2023, 3, 1)},
+		{date(2022, 12, 26), BucketWeek, date(2023, 1, 2)},
+		{date(2023, 1, 1), BucketMonth, date(2023, 2, 1)},
+		{date(2023, 10, 1), BucketQuarter, date(2024, 1, 1)},
+		{date(2023, 1, 1), BucketYear, date(2024, 1, 1)},
+	}
+	for _, test := range tests {
+		if got := nextBucket(test.start, test.bucket); !got.Equal(test.want) {
+			t.Errorf("nextBucket(%s, %s) = %s, want %s", test.start, test.bucket, got, test.want)
+		}
+	}
+}
+
+func TestBucketPeriod(t *testing.T) {
+	tests := []struct {
+		start  time.Time
+		bucket string
+		want   string
+	}{
+		{date(2023, 2, 1), BucketDay, "2023-02-01"},
+		{date(2023, 1, 30), BucketWeek, "2023-W05"},
+		// ISO weeks belong to the year of their Thursday
+		{date(2022, 12, 26), BucketWeek, "2022-W52"},
+		{date(2024, 12, 30), BucketWeek, "2025-W01"},
+		{date(2023, 2, 1), BucketMonth, "2023-02"},
+		{date(2023, 10, 1), BucketQuarter, "2023-Q4"},
+		{date(2023, 1, 1), BucketYear, "2023"},
+	}
+	for _, test := range tests {
+		if got := bucketPeriod(test.start, test.bucket); got != test.want {
+			t.Errorf("bucketPeriod(%s, %s) = %q, want %q", test.start, test.bucket, got, test.want)
+		}
+	}
+}
+
+func TestGetGameTimeline(t *testing.T) {
+	storage.SetStore(storage.NewMemoryStore())
+	store := storage.GetStore()
+	if _, err := store.UpsertGames(map[string]string{"10": "Hades"}); err != nil {
+		t.Fatal(err)
+	}
+	normalized := []models.NormalizedReport{
+		{Rating: models.RatingGold, Timestamp: date(2023, 1, 10)},
+		{Rating: models.RatingPlatinum, Timestamp: date(2023, 1, 20)},
+		{Rating: "", Timestamp: date(2023, 1, 25)},
+		{Rating: models.RatingBorked, Timestamp: date(2023, 3, 5)},
+		{Rating: models.RatingGold},
+	}
+	var reports []*models.Report
+	for i, report := range normalized {
+		reports = append(reports, models.NewReport("10", map[string]interface{}{"appId": "10", "key": i}, "V2", report))
+	}
+	if err := store.InsertReports(reports); err != nil {
+		t.Fatal(err)
+	}
+
+	timeline, err := GetGameTimeline("10", BucketMonth)
+	if err != nil || timeline == nil {
+		t.Fatalf("GetGameTimeline = %v, %v", timeline, err)
+	}
+	if timeline.Undated != 1 {
+		t.Errorf("undated = %d, want 1", timeline.Undated)
+	}
+
+	// February has no reports but is listed, so the periods are contiguous
+	want := []struct {
+		period  string
+		reports int64
+		score   float64
+		tier    string
+	}{
+		{"2023-01", 3, 0.88, models.RatingPlatinum},
+		{"2023-02", 0, 0, "pending"},
+		{"2023-03", 1, 0, models.RatingBorked},
+	}
+	if len(timeline.Buckets) != len(want) {
+		t.Fatalf("buckets = %+v, want %d", timeline.Buckets, len(want))
+	}
+	for i, bucket := range timeline.Buckets {
+		if bucket.Period != want[i].period || bucket.Reports != want[i].reports || bucket.Score != want[i].score || bucket.Tier != want[i].tier {
+			t.Errorf("bucket %d = %+v, want %+v", i, bucket, want[i])
+		}
+		if len(bucket.Ratings) != len(timelineRatings) {
+			t.Errorf("bucket %s lists %d ratings, want all %d", bucket.Period, len(bucket.Ratings), len(timelineRatings))
+		}
+	}
+	if january := timeline.Buckets[0].Ratings; january[models.RatingGold] != 1 || january[models.RatingPlatinum] != 1 {
+		t.Errorf("January ratings = %v, want 1 gold and 1 platinum", january)
+	}
+
+	missing, err := GetGameTimeline("20", BucketMonth)
+	if err != nil || missing != nil {
+		t.Errorf("GetGameTimeline of a missing game = %v, %v, want nil, nil", missing, err)
+	}
+}
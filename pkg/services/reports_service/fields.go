@@ -0,0 +1,155 @@
+package reports_service
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+)
+
+var fieldPattern = regexp.MustCompile(`^[A-Za-z0-9_]+(\.[A-Za-z0-9_]+)*$`)
+
+// ParseFields reads a comma-separated list of dotted field paths, e.g. "timestamp,hardware.gpu.vendor"
+func ParseFields(value string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !fieldPattern.MatchString(field) {
+			return nil, fmt.Errorf("invalid field: %s", field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// rawFields maps the paths of the system info of V2 reports as filed to the normalized fields holding it,
+// so fields=systemInfo.gpu reads the same in the normalized shape as in the raw one
+var rawFields = map[string]string{
+	"systemInfo.cpu":       "cpu",
+	"systemInfo.gpu":       "gpu",
+	"systemInfo.gpuDriver": "gpuDriver",
+	"systemInfo.kernel":    "kernel",
+	"systemInfo.os":        "os",
+	"systemInfo.ram":       "ram",
+}
+
+// normalizedField returns the path of the normalized shape a field is read from
+func normalizedField(field string) string {
+	if normalized, ok := rawFields[field]; ok {
+		return normalized
+	}
+	return field
+}
+
+// CheckFields returns an error for the first field the reports are not served with. Fields of the normalized
+// shape may be named by their raw path, e.g. systemInfo.gpu for gpu. The raw shape is the report as it was
+// filed, V1 and V2 reports have different fields, so its fields are not checked.
+func (o ReportOptions) CheckFields() error {
+	if o.Raw {
+		return nil
+	}
+
+	shape := reflect.TypeOf(models.NormalizedReport{})
+	if o.Versioned {
+		shape = reflect.TypeOf(models.Report{})
+	}
+	for _, field := range o.Fields {
+		if o.Versioned {
+			if !hasField(shape, strings.Split(field, ".")) {
+				return fmt.Errorf("unknown field: %s", field)
+			}
+			continue
+		}
+		if !hasField(shape, strings.Split(normalizedField(field), ".")) {
+			return fmt.Errorf("unknown field: %s, fields are paths in the normalized shape, or in the raw shape with raw=true", field)
+		}
+	}
+	return nil
+}
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// hasField reports whether values of the type have the path once encoded to JSON. Maps and interfaces
+// can hold any path, types encoding themselves, like times and hardware versions, are leaves.
+func hasField(t reflect.Type, path []string) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if len(path) == 0 {
+		return true
+	}
+	if t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Map, reflect.Interface:
+		return true
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if name == path[0] {
+				return hasField(field.Type, path[1:])
+			}
+		}
+	}
+	return false
+}
+
+// pickFields keeps only the given paths of a formatted report, leaving out those it does not have.
+// Each field is read from the path source returns for it and served under its own path.
+func pickFields(report interface{}, fields []string, source func(field string) string) map[string]interface{} {
+	var document map[string]interface{}
+	encoded, _ := json.Marshal(report)
+	json.Unmarshal(encoded, &document)
+
+	picked := make(map[string]interface{})
+	for _, field := range fields {
+		path := strings.Split(field, ".")
+
+		var value interface{} = document
+		found := true
+		for _, key := range strings.Split(source(field), ".") {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				found = false
+				break
+			}
+			if value, ok = object[key]; !ok {
+				found = false
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+
+		target := picked
+		for _, key := range path[:len(path)-1] {
+			next, ok := target[key].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				target[key] = next
+			}
+			target = next
+		}
+		target[path[len(path)-1]] = value
+	}
+	return picked
+}
@@ -0,0 +1,66 @@
+package reports_service
+
+import (
+	"testing"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/pagination"
+	"github.com/trsnaqe/protondb-api/pkg/storage"
+)
+
+func TestCheckFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ReportOptions
+		wantErr bool
+	}{
+		{"normalized fields", ReportOptions{Fields: []string{"timestamp", "gpu", "responses.verdict"}}, false},
+		{"parsed hardware", ReportOptions{Fields: []string{"hardware.gpu.vendor", "hardware.kernel", "proton.build"}}, false},
+		{"path into a version served as text", ReportOptions{Fields: []string{"hardware.kernel.Major"}}, true},
+		{"path into a time", ReportOptions{Fields: []string{"timestamp.wall"}}, true},
+		{"raw path in the normalized shape", ReportOptions{Fields: []string{"timestamp", "systemInfo.gpu", "responses.verdict"}}, false},
+		{"unknown raw path in the normalized shape", ReportOptions{Fields: []string{"systemInfo.screen"}}, true},
+		{"path into a text field", ReportOptions{Fields: []string{"gpu.vendor"}}, true},
+		{"hidden field", ReportOptions{Fields: []string{"ratingRank"}}, true},
+		{"raw shape", ReportOptions{Raw: true, Fields: []string{"systemInfo.gpu"}}, false},
+		{"versioned shape", ReportOptions{Versioned: true, Fields: []string{"Data.systemInfo.gpu", "Normalized.gpu"}}, false},
+		{"unknown versioned field", ReportOptions{Versioned: true, Fields: []string{"systemInfo.gpu"}}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.opts.CheckFields()
+			if (err != nil) != test.wantErr {
+				t.Errorf("CheckFields(%v) = %v, want error %v", test.opts.Fields, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestListReportsFields(t *testing.T) {
+	storage.SetStore(storage.NewMemoryStore())
+	normalized := models.NormalizedReport{
+		Rating:    models.RatingGold,
+		GPU:       "AMD RADV NAVI10",
+		Responses: map[string]interface{}{"verdict": "yes", "notes": "none"},
+	}
+	report := models.NewReport("10", map[string]interface{}{"appId": "10"}, "V2", normalized)
+	if err := storage.GetStore().InsertReports([]*models.Report{report}); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := ReportOptions{Fields: []string{"timestamp", "systemInfo.gpu", "responses.verdict"}}
+	reports, _, err := ListReports(nil, opts, pagination.Params{Limit: 10})
+	if err != nil || len(reports) != 1 {
+		t.Fatalf("ListReports = %v, %v, want 1 report", reports, err)
+	}
+	picked := reports[0].(map[string]interface{})
+	if len(picked) != 3 {
+		t.Errorf("picked %v, want timestamp, systemInfo and responses", picked)
+	}
+	if gpu := picked["systemInfo"].(map[string]interface{})["gpu"]; gpu != "AMD RADV NAVI10" {
+		t.Errorf("systemInfo.gpu = %v, want the normalized GPU", gpu)
+	}
+	if responses := picked["responses"].(map[string]interface{}); len(responses) != 1 || responses["verdict"] != "yes" {
+		t.Errorf("responses = %v, want the verdict only", responses)
+	}
+}
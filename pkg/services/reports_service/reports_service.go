@@ -1,90 +1,199 @@
 package reports_service
 
 import (
-	"log"
+	"bytes"
+	"encoding/json"
+	"sort"
 
 	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/pagination"
 	"github.com/trsnaqe/protondb-api/pkg/services/games_service"
 	"github.com/trsnaqe/protondb-api/pkg/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func GetReports(versioned bool) ([]interface{}, error) {
-	cursor, err := storage.GetAllReports()
-	if err != nil {
-		return nil, err
+// FormatReport returns the shape a report is served in: the whole stored document when versioned,
+// the raw ProtonDB data when raw, otherwise the normalized report
+func FormatReport(report models.Report, versioned bool, raw bool) interface{} {
+	switch {
+	case versioned:
+		return report
+	case raw || report.Normalized == nil:
+		return report.Data
+	default:
+		return report.Normalized
 	}
-	defer cursor.Close(nil)
+}
 
-	var reports []interface{}
-	for cursor.Next(nil) {
-		var report models.Report
-		if err := cursor.Decode(&report); err != nil {
-			return nil, err
-		}
+// ReportOptions shape a listing of reports
+type ReportOptions struct {
+	Versioned bool
+	Raw       bool
+	Version   string
+	Filter    storage.ReportFilter
+	Sort      storage.ReportSort
+	// Fields are the dotted paths kept in each served report, relative to the shape it is served in.
+	// Reports are served whole when empty.
+	Fields []string
+}
 
-		if versioned {
-			reports = append(reports, report)
-		} else {
-			reports = append(reports, report.Data)
-		}
+// storedFields returns where the fields are stored, so only those are read from the database.
+// The versioned shape is the stored document with other names, it is read whole.
+func (o ReportOptions) storedFields() []string {
+	if len(o.Fields) == 0 || o.Versioned {
+		return nil
 	}
 
-	return reports, nil
+	prefix := "normalized."
+	if o.Raw {
+		prefix = "data."
+	}
+
+	var stored []string
+	for _, field := range o.Fields {
+		if !o.Raw {
+			field = normalizedField(field)
+		}
+		stored = append(stored, prefix+field)
+	}
+	return stored
 }
 
-func GetReportsByGameID(gameID string, versioned bool, version string) ([]interface{}, error) {
-	reports, err := storage.GetReportsByGameID(gameID, version)
-	if err != nil {
-		return nil, err
+// ListReports returns a page of formatted reports of the given games, or of all games when appIDs is empty,
+// and the cursor of the next page, empty on the last page
+func ListReports(appIDs []string, opts ReportOptions, page pagination.Params) ([]interface{}, string, error) {
+	query := storage.ReportQuery{
+		AppIDs:  appIDs,
+		Version: opts.Version,
+		Filter:  opts.Filter,
+		Sort:    opts.Sort,
+		After:   page.After(),
+		Fields:  opts.storedFields(),
+		// Ask for one more report to know whether there is a next page
+		Limit: page.Limit + 1,
 	}
-	var data []interface{}
-	if versioned {
-		for _, report := range reports {
-			data = append(data, report)
-		}
-	} else {
-		for _, report := range reports {
-			data = append(data, report.Data)
+	if page.Cursor != nil && opts.Sort.Field != "" {
+		value, err := opts.Sort.DecodeValue(page.Cursor.Value)
+		if err != nil {
+			return nil, "", pagination.ErrInvalidCursor
 		}
+		query.AfterValue = value
+	}
+
+	reports, err := storage.GetStore().ListReports(query)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return data, nil
+	var next string
+	if len(reports) > page.Limit {
+		reports = reports[:page.Limit]
+		next = nextCursor(reports[page.Limit-1], opts.Sort)
+	}
+
+	data := make([]interface{}, 0, len(reports))
+	for _, report := range reports {
+		data = append(data, opts.format(report))
+	}
+	return data, next, nil
 }
 
-// search by title and get its reports with versioned version etc
-func GetReportsByTitleSearch(title string, versioned bool, version string, precision float64) ([]interface{}, error) {
-	games, err := games_service.SearchGameByTitle(title, precision)
-	if err != nil {
-		return nil, err
+// format returns the report in the shape and with the fields the options ask for
+func (o ReportOptions) format(report models.Report) interface{} {
+	formatted := FormatReport(report, o.Versioned, o.Raw)
+	if len(o.Fields) == 0 {
+		return formatted
 	}
 
-	var reports []interface{}
-	for _, game := range games {
-		gameReports, err := GetReportsByGameID(game.AppID, versioned, version)
-		if err != nil {
-			return nil, err
-		}
-		reports = append(reports, gameReports...)
+	source := func(field string) string { return field }
+	// Reports without a normalized shape are served as filed
+	if !o.Versioned && !o.Raw && report.Normalized != nil {
+		source = normalizedField
 	}
+	return pickFields(formatted, o.Fields, source)
+}
+
+// nextCursor returns the cursor of the page following the given report
+func nextCursor(last models.Report, sort storage.ReportSort) string {
+	cursor := pagination.Cursor{ID: last.ID}
+	if sort.Field != "" {
+		cursor.Sort = sort.String()
+		cursor.Value, _ = json.Marshal(sort.Value(last))
+	}
+	return cursor.Encode()
+}
 
-	return reports, nil
+func GetReports(opts ReportOptions, page pagination.Params) ([]interface{}, string, error) {
+	return ListReports(nil, opts, page)
 }
 
-func CreateNewReport(report map[string]interface{}, game *models.Game, reportVersion string) error {
-	newReport := &models.Report{
-		Data:          report,
-		ReportVersion: reportVersion,
+func GetReportsByGameID(gameID string, opts ReportOptions, page pagination.Params) ([]interface{}, string, error) {
+	return ListReports([]string{gameID}, opts, page)
+}
+
+// GameWithReports is a game found by a title search along with some of its reports, in the shape they are served in
+type GameWithReports struct {
+	ID      primitive.ObjectID
+	AppID   string
+	Title   *string
+	Reports []interface{}
+}
+
+// GetGamesWithReportsByTitle searches games by title and returns a page of the matches, best first,
+// each with up to reportsPerGame of its reports, and the cursor of the next page.
+// Both title searches, of games with reports and of reports, are served by it.
+func GetGamesWithReportsByTitle(title string, precision float64, reportsPerGame int, opts ReportOptions, page pagination.Params) ([]GameWithReports, string, error) {
+	matches := games_service.SearchGames(title, precision)
+	if page.Cursor != nil {
+		var afterScore float64
+		if err := json.Unmarshal(page.Cursor.Value, &afterScore); err != nil {
+			return nil, "", pagination.ErrInvalidCursor
+		}
+		// Matches are ordered by descending score, then _id
+		start := sort.Search(len(matches), func(i int) bool {
+			return matches[i].Score < afterScore ||
+				(matches[i].Score == afterScore && bytes.Compare(matches[i].ID[:], page.Cursor.ID[:]) > 0)
+		})
+		matches = matches[start:]
+	}
+
+	var next string
+	if len(matches) > page.Limit {
+		matches = matches[:page.Limit]
+		last := matches[page.Limit-1]
+		score, _ := json.Marshal(last.Score)
+		next = pagination.Cursor{ID: last.ID, Sort: "score", Value: score}.Encode()
 	}
-	createdReport, err := storage.CreateReport(newReport, game)
-	if err != nil {
-		log.Println("Error creating report:", err)
-		return err
+
+	if len(matches) == 0 {
+		return []GameWithReports{}, "", nil
+	}
+
+	query := storage.GamesWithReportsQuery{Version: opts.Version, Filter: opts.Filter, ReportsPerGame: reportsPerGame}
+	for _, match := range matches {
+		query.GameIDs = append(query.GameIDs, match.ID)
 	}
-	err = games_service.AddReportToGame(game, createdReport)
+	stored, err := storage.GetStore().GetGamesWithReports(query)
 	if err != nil {
-		log.Println("Error adding report to game:", err)
-		return err
+		return nil, "", err
+	}
+	byID := make(map[primitive.ObjectID]models.MatchedGame, len(stored))
+	for _, game := range stored {
+		byID[game.ID] = game
 	}
 
-	return nil
+	games := make([]GameWithReports, 0, len(matches))
+	for _, match := range matches {
+		// Games deleted since the search index was built are left out
+		matched, ok := byID[match.ID]
+		if !ok {
+			continue
+		}
+		game := GameWithReports{ID: matched.ID, AppID: matched.AppID, Title: matched.Title, Reports: []interface{}{}}
+		for _, report := range matched.MatchedReports {
+			game.Reports = append(game.Reports, opts.format(report))
+		}
+		games = append(games, game)
+	}
+	return games, next, nil
 }
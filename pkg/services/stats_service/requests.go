@@ -0,0 +1,34 @@
+package stats_service
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Request counters since the API started
+var (
+	requestCount        int64
+	totalResponseTimeNs int64
+)
+
+// RequestStats are the number of requests served since the API started and their average response time
+type RequestStats struct {
+	Count                 int64   `json:"count"`
+	AverageResponseTimeMs float64 `json:"averageResponseTimeMs"`
+}
+
+// RecordRequest counts a served request and the time it took
+func RecordRequest(duration time.Duration) {
+	atomic.AddInt64(&requestCount, 1)
+	atomic.AddInt64(&totalResponseTimeNs, int64(duration))
+}
+
+func GetRequestStats() RequestStats {
+	count := atomic.LoadInt64(&requestCount)
+	stats := RequestStats{Count: count}
+	if count > 0 {
+		average := time.Duration(atomic.LoadInt64(&totalResponseTimeNs) / count)
+		stats.AverageResponseTimeMs = float64(average.Microseconds()) / 1000
+	}
+	return stats
+}
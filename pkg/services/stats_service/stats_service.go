@@ -7,17 +7,17 @@ import (
 )
 
 func GetStats() (map[string]interface{}, error) {
-	totalGameCount, err := storage.GetTotalGamesCount()
+	totalGameCount, err := storage.GetStore().GetTotalGamesCount()
 	if err != nil {
 		return nil, err
 	}
 
-	totalReportsCount, err := storage.GetTotalReportsCount()
+	totalReportsCount, err := storage.GetStore().GetTotalReportsCount()
 	if err != nil {
 		return nil, err
 	}
 
-	lastProcessedData, err := storage.GetLastProcessedData()
+	lastProcessedData, err := storage.GetStore().GetLastProcessedData()
 	if err != nil {
 		return nil, err
 	}
@@ -28,6 +28,18 @@ func GetStats() (map[string]interface{}, error) {
 		lastDate = lastProcessedData.LastProcessedTime
 	}
 
+	// Precomputed after each ingestion, nil until the first computation is done
+	datasetStats, err := storage.GetStore().GetDatasetStats()
+	if err != nil {
+		return nil, err
+	}
+
+	// The last regression detection, nil until regressions were detected once
+	regressionRun, err := storage.GetStore().GetRegressionRun()
+	if err != nil {
+		return nil, err
+	}
+
 	updateInterval := background_services.GetUpdateInterval()
 
 	timeRemaining := background_services.TimeRemainingForNextUpdate(updateInterval)
@@ -40,6 +52,10 @@ func GetStats() (map[string]interface{}, error) {
 		"lastProcessedFile":         lastFile,
 		"lastProcessedDate":         lastDate,
 		"timeRemainingToNextUpdate": totalTimeRemainingStr,
+		"ingestion":                 background_services.GetIngestProgress(),
+		"requests":                  GetRequestStats(),
+		"dataset":                   datasetStats,
+		"regressions":               regressionRun,
 	}
 
 	return stats, nil
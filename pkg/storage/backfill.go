@@ -0,0 +1,369 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/proton"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// backfillReports walks the reports matching filter and applies the $set returned by compute to each,
+// in bulk writes of 1000. Reports compute fails on are logged and skipped, as are updates rejected
+// by a unique index. It returns the number of reports modified and of duplicates rejected.
+func backfillReports(filter bson.M, compute func(report *models.Report) (bson.M, error)) (int64, int64, error) {
+	ctx := context.Background()
+
+	cursor, err := reportsCollection.Find(ctx, filter)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var modified, duplicates int64
+	var writes []mongo.WriteModel
+
+	flush := func() error {
+		if len(writes) == 0 {
+			return nil
+		}
+		result, err := reportsCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		if result != nil {
+			modified += result.ModifiedCount
+		}
+		writes = writes[:0]
+
+		var bulkErr mongo.BulkWriteException
+		if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+			for _, writeErr := range bulkErr.WriteErrors {
+				if !mongo.IsDuplicateKeyError(writeErr) {
+					return err
+				}
+				duplicates++
+			}
+			return nil
+		}
+		return err
+	}
+
+	for cursor.Next(ctx) {
+		var report models.Report
+		if err := cursor.Decode(&report); err != nil {
+			return modified, duplicates, err
+		}
+
+		set, err := compute(&report)
+		if err != nil {
+			log.Printf("Cannot backfill report %s: %v", report.ID.Hex(), err)
+			continue
+		}
+
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": report.ID}).
+			SetUpdate(bson.M{"$set": set}))
+		if len(writes) >= 1000 {
+			if err := flush(); err != nil {
+				return modified, duplicates, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return modified, duplicates, err
+	}
+	if err := flush(); err != nil {
+		return modified, duplicates, err
+	}
+
+	return modified, duplicates, nil
+}
+
+// BackfillReportHashes computes the hash of the reports stored without one. Reports duplicating an
+// already hashed report are rejected by the unique index and left unhashed. It returns the number of reports hashed.
+func BackfillReportHashes() (int64, error) {
+	hashed, duplicates, err := backfillReports(bson.M{"hash": bson.M{"$exists": false}}, func(report *models.Report) (bson.M, error) {
+		appID, err := models.ReportAppID(report.ReportVersion, report.Data)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"hash": models.HashReport(appID, report.ReportVersion, report.Data)}, nil
+	})
+
+	if hashed > 0 || duplicates > 0 {
+		log.Printf("Hashed %d reports, %d duplicates left unhashed", hashed, duplicates)
+	}
+	return hashed, err
+}
+
+// BackfillNormalizedReports stores the normalized form of the reports ingested before it existed,
+// or before it had the fields reports are filtered and sorted on. It returns the number of reports normalized.
+func BackfillNormalizedReports() (int64, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"normalized": bson.M{"$exists": false}},
+		bson.M{"normalized.gpuVendor": bson.M{"$exists": false}},
+		bson.M{"normalized.ratingRank": bson.M{"$exists": false}},
+	}}
+	normalized, _, err := backfillReports(filter, func(report *models.Report) (bson.M, error) {
+		appID, err := models.ReportAppID(report.ReportVersion, report.Data)
+		if err != nil {
+			return nil, err
+		}
+		normalizedReport, err := models.NormalizeReportData(appID, report.ReportVersion, report.Data)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"normalized": normalizedReport}, nil
+	})
+
+	if normalized > 0 {
+		log.Printf("Normalized %d reports", normalized)
+	}
+	return normalized, err
+}
+
+// BackfillReportHardware parses the system info of the reports normalized before it was parsed into
+// structured fields, setting only the fields derived from it. It returns the number of reports updated.
+func BackfillReportHardware() (int64, error) {
+	filter := bson.M{"normalized": bson.M{"$exists": true}, "normalized.hardware": bson.M{"$exists": false}}
+	updated, _, err := backfillReports(filter, func(report *models.Report) (bson.M, error) {
+		if report.Normalized == nil {
+			return nil, errors.New("report is not normalized")
+		}
+		report.Normalized.ParseHardware()
+		return bson.M{
+			"normalized.hardware":      report.Normalized.Hardware,
+			"normalized.gpuVendor":     report.Normalized.GPUVendor,
+			"normalized.kernelVersion": report.Normalized.KernelVersion,
+		}, nil
+	})
+
+	if updated > 0 {
+		log.Printf("Parsed the hardware of %d reports", updated)
+	}
+	return updated, err
+}
+
+// BackfillReportProtonVersions parses the Proton version of the reports normalized before it was parsed,
+// setting only the parsed version. It returns the number of reports updated.
+func BackfillReportProtonVersions() (int64, error) {
+	filter := bson.M{"normalized": bson.M{"$exists": true}, "normalized.proton": bson.M{"$exists": false}}
+	updated, _, err := backfillReports(filter, func(report *models.Report) (bson.M, error) {
+		if report.Normalized == nil {
+			return nil, errors.New("report is not normalized")
+		}
+		return bson.M{"normalized.proton": proton.Parse(report.Normalized.ProtonVersion)}, nil
+	})
+
+	if updated > 0 {
+		log.Printf("Parsed the Proton version of %d reports", updated)
+	}
+	return updated, err
+}
+
+// BackfillReportGameRefs stores the app ID and game ID on the reports ingested when only the game listed
+// its reports. The app ID is read from the report data, so reports that never got linked to their game are
+// covered too. It returns the number of reports updated.
+func BackfillReportGameRefs() (int64, error) {
+	filter := bson.M{"appId": bson.M{"$exists": false}}
+	if count, err := reportsCollection.CountDocuments(context.Background(), filter, options.Count().SetLimit(1)); err != nil || count == 0 {
+		return 0, err
+	}
+
+	// Load the game ID of every app ID once instead of looking it up for each report
+	cursor, err := gamesCollection.Find(context.Background(), bson.M{}, options.Find().SetProjection(bson.M{"appId": 1}))
+	if err != nil {
+		return 0, err
+	}
+	var games []models.Game
+	if err := cursor.All(context.Background(), &games); err != nil {
+		return 0, err
+	}
+	gameIDs := make(map[string]primitive.ObjectID, len(games))
+	for _, game := range games {
+		gameIDs[game.AppID] = game.ID
+	}
+
+	updated, _, err := backfillReports(filter, func(report *models.Report) (bson.M, error) {
+		appID, err := models.ReportAppID(report.ReportVersion, report.Data)
+		if err != nil {
+			return nil, err
+		}
+		set := bson.M{"appId": appID}
+		if gameID, ok := gameIDs[appID]; ok {
+			set["gameId"] = gameID
+		}
+		return set, nil
+	})
+
+	if updated > 0 {
+		log.Printf("Referenced the game on %d reports", updated)
+	}
+	return updated, err
+}
+
+// BackfillGameTitles builds the title aliases of the games stored before games kept them, from the titles
+// their reports were filed under, and sets their canonical title. Games without normalized reports keep
+// their title. It returns the number of games updated.
+func BackfillGameTitles() (int64, error) {
+	ctx := context.Background()
+
+	filter := bson.M{"titles": bson.M{"$exists": false}}
+	if count, err := gamesCollection.CountDocuments(ctx, filter, options.Count().SetLimit(1)); err != nil || count == 0 {
+		return 0, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"appId": bson.M{"$exists": true}, "normalized.title": bson.M{"$nin": bson.A{nil, ""}}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "appId", Value: "$appId"}, {Key: "title", Value: "$normalized.title"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "firstSeen", Value: bson.D{{Key: "$min", Value: "$normalized.timestamp"}}},
+			{Key: "lastSeen", Value: bson.D{{Key: "$max", Value: "$normalized.timestamp"}}},
+		}}},
+	}
+	cursor, err := reportsCollection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	aliases := make(map[string][]models.TitleAlias)
+	for cursor.Next(ctx) {
+		var group struct {
+			ID struct {
+				AppID string `bson:"appId"`
+				Title string `bson:"title"`
+			} `bson:"_id"`
+			Count     int64     `bson:"count"`
+			FirstSeen time.Time `bson:"firstSeen"`
+			LastSeen  time.Time `bson:"lastSeen"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return 0, err
+		}
+		aliases[group.ID.AppID] = append(aliases[group.ID.AppID], models.TitleAlias{
+			Title:     group.ID.Title,
+			Count:     group.Count,
+			FirstSeen: group.FirstSeen,
+			LastSeen:  group.LastSeen,
+		})
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, err
+	}
+
+	var updated int64
+	var writes []mongo.WriteModel
+	flush := func() error {
+		if len(writes) == 0 {
+			return nil
+		}
+		result, err := gamesCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		if result != nil {
+			updated += result.ModifiedCount
+		}
+		writes = writes[:0]
+		return err
+	}
+
+	for appID, gameAliases := range aliases {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			// Games that got aliases from an ingestion in the meantime are left as they are
+			SetFilter(bson.M{"appId": appID, "titles": bson.M{"$exists": false}}).
+			SetUpdate(bson.M{"$set": bson.M{"titles": gameAliases, "title": models.CanonicalTitle(gameAliases)}}))
+		if len(writes) == 1000 {
+			if err := flush(); err != nil {
+				return updated, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return updated, err
+	}
+
+	if updated > 0 {
+		log.Printf("Built the title aliases of %d games", updated)
+	}
+	return updated, nil
+}
+
+// MergeDuplicateGames merges the games sharing an app ID into the first one stored, so appId can be
+// indexed as unique: their title aliases are merged, and their reports are moved to the game kept.
+// It returns the number of games deleted.
+func MergeDuplicateGames() (int64, error) {
+	ctx := context.Background()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"appId": bson.M{"$type": "string"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$appId"},
+			{Key: "ids", Value: bson.D{{Key: "$push", Value: "$_id"}}},
+		}}},
+		{{Key: "$match", Value: bson.M{"ids.1": bson.M{"$exists": true}}}},
+	}
+	cursor, err := gamesCollection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return 0, err
+	}
+	var groups []struct {
+		AppID string               `bson:"_id"`
+		IDs   []primitive.ObjectID `bson:"ids"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, group := range groups {
+		keep, duplicates := group.IDs[0], group.IDs[1:]
+
+		cursor, err := gamesCollection.Find(ctx, bson.M{"_id": bson.M{"$in": group.IDs}}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+		if err != nil {
+			return deleted, err
+		}
+		var games []models.Game
+		if err := cursor.All(ctx, &games); err != nil {
+			return deleted, err
+		}
+
+		var titles []models.TitleAlias
+		var reports []primitive.ObjectID
+		for _, game := range games {
+			for _, alias := range game.Titles {
+				titles = models.AddTitleAlias(titles, alias)
+			}
+			reports = append(reports, game.Reports...)
+		}
+		set := bson.M{"titles": titles}
+		if canonical := models.CanonicalTitle(titles); canonical != "" {
+			set["title"] = canonical
+		}
+
+		if _, err := reportsCollection.UpdateMany(ctx, bson.M{"gameId": bson.M{"$in": duplicates}}, bson.M{"$set": bson.M{"gameId": keep}}); err != nil {
+			return deleted, err
+		}
+		update := bson.M{"$set": set}
+		if len(reports) > 0 {
+			update["$addToSet"] = bson.M{"reports": bson.M{"$each": reports}}
+		}
+		if _, err := gamesCollection.UpdateOne(ctx, bson.M{"_id": keep}, update); err != nil {
+			return deleted, err
+		}
+		result, err := gamesCollection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": duplicates}})
+		if err != nil {
+			return deleted, err
+		}
+		deleted += result.DeletedCount
+	}
+
+	if deleted > 0 {
+		log.Printf("Merged %d duplicate games", deleted)
+	}
+	return deleted, nil
+}
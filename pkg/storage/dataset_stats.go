@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// datasetStatsID is the _id of the dataset stats document in the stats collection
+const datasetStatsID = "dataset"
+
+// ComputeDatasetStats computes the analytics over all reports in a single aggregation, all but the tiers
+// of the games which need their summaries. Top lists are cut to their top most common values.
+func ComputeDatasetStats(top int) (*models.DatasetStats, error) {
+	topGames := distributionStages("appId", top, false)
+	topGames = append(topGames,
+		bson.M{"$lookup": bson.M{
+			"from":         gamesCollection.Name(),
+			"localField":   "_id",
+			"foreignField": "appId",
+			"as":           "game",
+		}},
+		bson.M{"$project": bson.M{"count": 1, "title": bson.M{"$ifNull": bson.A{bson.M{"$first": "$game.title"}, ""}}}},
+	)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"normalized": bson.M{"$exists": true}}}},
+		{{Key: "$facet", Value: bson.M{
+			"reportsPerMonth":   monthStages(),
+			"topGames":          topGames,
+			"ratings":           distributionStages("normalized.rating", 0, false),
+			"topGpus":           distributionStages("normalized.gpu", top, false),
+			"topDistros":        distributionStages("normalized.os", top, false),
+			"topProtonVersions": distributionStages("normalized.protonVersion", top, false),
+		}}},
+	}
+
+	cursor, err := reportsCollection.Aggregate(context.Background(), pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	stats := &models.DatasetStats{ComputedAt: time.Now().UTC()}
+	if cursor.Next(context.Background()) {
+		if err := cursor.Decode(stats); err != nil {
+			return nil, err
+		}
+	}
+	return stats, cursor.Err()
+}
+
+// StreamGameRatings calls fn with the ratings of the reports of each game, one game at a time
+func StreamGameRatings(fn func(appID string, ratings []models.ReportRating) error) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"appId": bson.M{"$exists": true}, "normalized": bson.M{"$exists": true}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     "$appId",
+			"ratings": bson.M{"$push": bson.M{"rating": "$normalized.rating", "timestamp": "$normalized.timestamp"}},
+		}}},
+	}
+
+	cursor, err := reportsCollection.Aggregate(context.Background(), pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var game struct {
+			AppID   string                `bson:"_id"`
+			Ratings []models.ReportRating `bson:"ratings"`
+		}
+		if err := cursor.Decode(&game); err != nil {
+			return err
+		}
+		if err := fn(game.AppID, game.Ratings); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// SaveDatasetStats replaces the stored dataset stats
+func SaveDatasetStats(stats *models.DatasetStats) error {
+	_, err := statsCollection.ReplaceOne(context.Background(), bson.M{"_id": datasetStatsID}, stats, options.Replace().SetUpsert(true))
+	return err
+}
+
+// GetDatasetStats returns the stored dataset stats, nil if they were never computed
+func GetDatasetStats() (*models.DatasetStats, error) {
+	var stats models.DatasetStats
+	err := statsCollection.FindOne(context.Background(), bson.M{"_id": datasetStatsID}).Decode(&stats)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &stats, nil
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -16,13 +17,18 @@ var (
 	gamesCollection         *mongo.Collection
 	reportsCollection       *mongo.Collection
 	processStatusCollection *mongo.Collection
+	migrationsCollection    *mongo.Collection
+	statsCollection         *mongo.Collection
+	regressionsCollection   *mongo.Collection
 )
 
+// ConnectDB connects to the database. Indexes and data backfills are applied by RunMigrations.
 func ConnectDB() error {
 	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
 	dbURI := os.Getenv("DB_URI")
 	opts := options.Client().ApplyURI(dbURI).SetServerAPIOptions(serverAPI)
-	client, err := mongo.Connect(context.TODO(), opts)
+	var err error
+	client, err = mongo.Connect(context.TODO(), opts)
 	if err != nil {
 		panic(err)
 	}
@@ -35,12 +41,9 @@ func ConnectDB() error {
 	gamesCollection = client.Database("protondb_reports").Collection("games")
 	reportsCollection = client.Database("protondb_reports").Collection("reports")
 	processStatusCollection = client.Database("protondb_reports").Collection("process_status")
-
-	// Ensure the index on the title field
-	if err := ensureTitleIndex(); err != nil {
-		log.Printf("Error creating index: %v", err)
-		return err
-	}
+	migrationsCollection = client.Database("protondb_reports").Collection("schema_migrations")
+	statsCollection = client.Database("protondb_reports").Collection("stats")
+	regressionsCollection = client.Database("protondb_reports").Collection("regressions")
 
 	return nil
 }
@@ -64,3 +67,129 @@ func ensureTitleIndex() error {
 	_, err := gamesCollection.Indexes().CreateOne(context.TODO(), indexModel)
 	return err
 }
+
+// ensureGameAppIDIndex creates the unique index on the appId field of the games collection if it doesn't exist,
+// so concurrent upserts of a new game cannot create it twice
+func ensureGameAppIDIndex() error {
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "appId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := gamesCollection.Indexes().CreateOne(context.TODO(), indexModel)
+	return err
+}
+
+// dropTitleTextIndex drops the text index ensureTitleIndex created, if it exists
+func dropTitleTextIndex() error {
+	_, err := gamesCollection.Indexes().DropOne(context.TODO(), "title_text")
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && commandErr.Name == "IndexNotFound" {
+		return nil
+	}
+	return err
+}
+
+// ensureReportHashIndex creates the unique index on the hash field of the reports collection if it doesn't exist.
+// Reports that could not be hashed have no hash field and are left out of the index.
+func ensureReportHashIndex() error {
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "hash", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"hash": bson.M{"$exists": true}}),
+	}
+
+	_, err := reportsCollection.Indexes().CreateOne(context.TODO(), indexModel)
+	return err
+}
+
+// ensureReportFilterIndexes creates the indexes behind the report filters and sorts if they don't exist.
+// Each ends with _id so a listing can be paginated without sorting in memory.
+func ensureReportFilterIndexes() error {
+	fields := []string{
+		"normalized.gpuVendor",
+		"normalized.rating",
+		"normalized.ratingRank",
+		"normalized.verdict",
+		"normalized.kernelVersion",
+		"normalized.timestamp",
+		"normalized.protonVersion",
+	}
+
+	var indexModels []mongo.IndexModel
+	for _, field := range fields {
+		indexModels = append(indexModels, mongo.IndexModel{
+			Keys: bson.D{{Key: field, Value: 1}, {Key: "_id", Value: 1}},
+		})
+	}
+
+	_, err := reportsCollection.Indexes().CreateMany(context.TODO(), indexModels)
+	return err
+}
+
+// ensureReportHardwareIndexes creates the indexes behind the filters on the structured hardware fields if they don't exist
+func ensureReportHardwareIndexes() error {
+	fields := []string{
+		"normalized.hardware.driver.vendor",
+		"normalized.hardware.driver.version.code",
+		"normalized.hardware.distro.name",
+		"normalized.hardware.cpu.vendor",
+		"normalized.hardware.ramGb",
+	}
+
+	var indexModels []mongo.IndexModel
+	for _, field := range fields {
+		indexModels = append(indexModels, mongo.IndexModel{
+			Keys: bson.D{{Key: field, Value: 1}, {Key: "_id", Value: 1}},
+		})
+	}
+
+	_, err := reportsCollection.Indexes().CreateMany(context.TODO(), indexModels)
+	return err
+}
+
+// ensureReportProtonIndexes creates the indexes behind the Proton version filters if they don't exist
+func ensureReportProtonIndexes() error {
+	indexModels := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "normalized.proton.code", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "normalized.proton.build", Value: 1}, {Key: "_id", Value: 1}}},
+	}
+
+	_, err := reportsCollection.Indexes().CreateMany(context.TODO(), indexModels)
+	return err
+}
+
+// ensureRegressionIndexes creates the index the regressions of a game are looked up with if it doesn't exist
+func ensureRegressionIndexes() error {
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "appId", Value: 1}, {Key: "_id", Value: 1}},
+	}
+
+	_, err := regressionsCollection.Indexes().CreateOne(context.TODO(), indexModel)
+	return err
+}
+
+// ensureRegressionRunIndexes creates the indexes the regressions of the last run, and of a game in it, are
+// listed with if they don't exist
+func ensureRegressionRunIndexes() error {
+	indexModels := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "runId", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "runId", Value: 1}, {Key: "appId", Value: 1}, {Key: "_id", Value: 1}}},
+	}
+
+	_, err := regressionsCollection.Indexes().CreateMany(context.TODO(), indexModels)
+	return err
+}
+
+// ensureReportGameIndexes creates the indexes reports are looked up by their game with if they don't exist.
+// The appId index ends with _id so the reports of a game can be paginated without sorting in memory.
+func ensureReportGameIndexes() error {
+	indexModels := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "appId", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "gameId", Value: 1}}},
+	}
+
+	_, err := reportsCollection.Indexes().CreateMany(context.TODO(), indexModels)
+	return err
+}
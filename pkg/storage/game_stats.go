@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetGameStats computes the statistics of the reports of a game in a single aggregation.
+// Distributions other than ratings and GPU vendors are cut to their top most common values.
+func GetGameStats(appID string, top int) (*models.GameStats, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"appId": appID, "normalized": bson.M{"$exists": true}}}},
+		{{Key: "$facet", Value: bson.M{
+			"total":           bson.A{bson.M{"$count": "count"}},
+			"reportsPerMonth": monthStages(),
+			"ratings":         distributionStages("normalized.rating", 0, false),
+			"protonVersions":  distributionStages("normalized.protonVersion", top, false),
+			"gpuVendors":      distributionStages("normalized.gpuVendor", 0, false),
+			"distros":         distributionStages("normalized.os", top, false),
+			"tweaks":          distributionStages("normalized.tweaks", top, true),
+			"launchOptions":   distributionStages("normalized.launchOptions", top, false),
+		}}},
+	}
+
+	cursor, err := reportsCollection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var facets struct {
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		ReportsPerMonth []models.StatCount `bson:"reportsPerMonth"`
+		Ratings         []models.StatCount `bson:"ratings"`
+		ProtonVersions  []models.StatCount `bson:"protonVersions"`
+		GPUVendors      []models.StatCount `bson:"gpuVendors"`
+		Distros         []models.StatCount `bson:"distros"`
+		Tweaks          []models.StatCount `bson:"tweaks"`
+		LaunchOptions   []models.StatCount `bson:"launchOptions"`
+	}
+	if cursor.Next(context.Background()) {
+		if err := cursor.Decode(&facets); err != nil {
+			return nil, err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	stats := &models.GameStats{
+		AppID:           appID,
+		ReportsPerMonth: facets.ReportsPerMonth,
+		Ratings:         facets.Ratings,
+		ProtonVersions:  facets.ProtonVersions,
+		GPUVendors:      facets.GPUVendors,
+		Distros:         facets.Distros,
+		Tweaks:          facets.Tweaks,
+		LaunchOptions:   facets.LaunchOptions,
+	}
+	if len(facets.Total) > 0 {
+		stats.Total = facets.Total[0].Count
+	}
+	return stats, nil
+}
+
+// distributionStages count the reports by the value of a field, most common first. Reports without
+// the field are left out, array fields are counted by element when unwind is set.
+func distributionStages(field string, limit int, unwind bool) bson.A {
+	stages := bson.A{}
+	if unwind {
+		stages = append(stages, bson.M{"$unwind": "$" + field})
+	}
+	stages = append(stages,
+		bson.M{"$match": bson.M{field: bson.M{"$nin": bson.A{nil, ""}}}},
+		bson.M{"$group": bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	)
+	if limit > 0 {
+		stages = append(stages, bson.M{"$limit": limit})
+	}
+	return stages
+}
+
+// monthStages count the reports by the month of their timestamp, in order
+func monthStages() bson.A {
+	return bson.A{
+		bson.M{"$match": bson.M{"normalized.timestamp": bson.M{"$gt": time.Time{}}}},
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"$dateToString": bson.M{"format": "%Y-%m", "date": "$normalized.timestamp"}},
+			"count": bson.M{"$sum": 1},
+		}},
+		bson.M{"$sort": bson.M{"_id": 1}},
+	}
+}
+
+// statCounter counts values the way the distributions of GetGameStats do
+type statCounter map[string]int64
+
+func (c statCounter) add(value string) {
+	if value != "" {
+		c[value]++
+	}
+}
+
+// top returns the counts by descending count, then value, cut to limit values unless limit is 0
+func (c statCounter) top(limit int) []models.StatCount {
+	counts := make([]models.StatCount, 0, len(c))
+	for value, count := range c {
+		counts = append(counts, models.StatCount{Value: value, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+	if limit > 0 && len(counts) > limit {
+		counts = counts[:limit]
+	}
+	return counts
+}
@@ -12,18 +12,6 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func CreateGame(game *models.Game) error {
-	if game.Reports == nil {
-		game.Reports = []primitive.ObjectID{}
-	}
-	result, err := gamesCollection.InsertOne(context.Background(), game)
-	if err != nil {
-		return err
-	}
-	game.ID = result.InsertedID.(primitive.ObjectID)
-	return nil
-}
-
 func GetGameByAppID(appID string) (*models.Game, error) {
 	filter := bson.M{"appId": appID}
 	options := options.FindOne().SetProjection(bson.M{"reports": 0})
@@ -40,21 +28,6 @@ func GetGameByAppID(appID string) (*models.Game, error) {
 	return &game, nil
 }
 
-func GetGameByAppIDWithReports(appID string) (*models.Game, error) {
-	filter := bson.M{"appId": appID}
-
-	var game models.Game
-	err := gamesCollection.FindOne(context.Background(), filter).Decode(&game)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
-		}
-		return nil, err
-	}
-
-	return &game, nil
-}
-
 func GetTotalGamesCount() (int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -68,107 +41,160 @@ func GetTotalGamesCount() (int64, error) {
 	return count, nil
 }
 
-func UpdateGame(game *models.Game) error {
-	filter := bson.M{"_id": game.ID}
-	update := bson.M{"$set": bson.M{"appId": game.AppID, "title": game.Title, "reports": game.Reports}}
-
-	_, err := gamesCollection.UpdateOne(context.Background(), filter, update)
-	return err
-}
+func GetAllGames() (*mongo.Cursor, error) {
+	options := options.Find().SetProjection(bson.M{"reports": 0})
 
-func GetGameByID(gameID string) (*models.Game, error) {
-	objectID, err := primitive.ObjectIDFromHex(gameID)
+	cursor, err := gamesCollection.Find(context.Background(), bson.M{}, options)
 	if err != nil {
 		return nil, err
 	}
 
-	filter := bson.M{"_id": objectID}
+	return cursor, nil
+}
 
-	options := options.FindOne().SetProjection(bson.M{"reports": 0})
+// ListGames returns up to limit games with an _id greater than after, in _id order
+func ListGames(after primitive.ObjectID, limit int) ([]models.Game, error) {
+	filter := bson.M{}
+	if !after.IsZero() {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+	findOptions := options.Find().
+		SetProjection(bson.M{"reports": 0}).
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
 
-	var game models.Game
-	err = gamesCollection.FindOne(context.Background(), filter, options).Decode(&game)
+	cursor, err := gamesCollection.Find(context.Background(), filter, findOptions)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
-		}
 		return nil, err
 	}
 
-	return &game, nil
+	games := []models.Game{}
+	if err := cursor.All(context.Background(), &games); err != nil {
+		return nil, err
+	}
+	return games, nil
 }
 
-func GetAllGames() (*mongo.Cursor, error) {
-	options := options.Find().SetProjection(bson.M{"reports": 0})
+// GetGamesByIDs returns the games with the given IDs, in no particular order
+func GetGamesByIDs(ids []primitive.ObjectID) ([]models.Game, error) {
+	findOptions := options.Find().SetProjection(bson.M{"reports": 0})
 
-	cursor, err := gamesCollection.Find(context.Background(), bson.M{}, options)
+	cursor, err := gamesCollection.Find(context.Background(), bson.M{"_id": bson.M{"$in": ids}}, findOptions)
 	if err != nil {
 		return nil, err
 	}
 
-	return cursor, nil
+	games := []models.Game{}
+	if err := cursor.All(context.Background(), &games); err != nil {
+		return nil, err
+	}
+	return games, nil
 }
 
-func DeleteGame(gameID string) error {
-	objectID, err := primitive.ObjectIDFromHex(gameID)
-	if err != nil {
-		return err
+// UpsertGames creates the missing games and returns the games keyed by app ID. titles maps app IDs to the
+// title a missing game is created with; the titles of existing games are left to AddTitleAliases.
+func UpsertGames(titles map[string]string) (map[string]*models.Game, error) {
+	if len(titles) == 0 {
+		return map[string]*models.Game{}, nil
 	}
 
-	filter := bson.M{"_id": objectID}
-	_, err = gamesCollection.DeleteOne(context.Background(), filter)
-	return err
-}
-func InsertReport(game *models.Game, reportID primitive.ObjectID) error {
-	filter := bson.M{"_id": game.ID}
+	appIDs := make([]string, 0, len(titles))
+	writes := make([]mongo.WriteModel, 0, len(titles))
+	for appID, title := range titles {
+		appIDs = append(appIDs, appID)
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"appId": appID}).
+			SetUpdate(bson.M{"$setOnInsert": bson.M{"title": title, "reports": bson.A{}}}).
+			SetUpsert(true))
+	}
 
-	checkUpdate := bson.M{"$setOnInsert": bson.M{"reports": bson.A{}}}
-	_, err := gamesCollection.UpdateOne(context.Background(), filter, checkUpdate, options.Update().SetUpsert(true))
+	_, err := gamesCollection.BulkWrite(context.Background(), writes, options.BulkWrite().SetOrdered(false))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	update := bson.M{"$push": bson.M{"reports": reportID}}
-	_, err = gamesCollection.UpdateOne(context.Background(), filter, update)
+	cursor, err := gamesCollection.Find(context.Background(), bson.M{"appId": bson.M{"$in": appIDs}}, options.Find().SetProjection(bson.M{"reports": 0}))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	defer cursor.Close(context.Background())
+
+	games := make(map[string]*models.Game, len(appIDs))
+	for cursor.Next(context.Background()) {
+		var game models.Game
+		if err := cursor.Decode(&game); err != nil {
+			return nil, err
+		}
+		games[game.AppID] = &game
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return games, nil
 }
 
-func ChangeTitle(gameID primitive.ObjectID, newTitle string) error {
-	filter := bson.M{"_id": gameID}
-	update := bson.M{"$set": bson.M{"title": newTitle}}
+// AddTitleAliases merges the titles observed in a batch of inserted reports into the aliases of their games,
+// then sets the canonical title of each game. titles maps app IDs to the aliases observed, with the number of
+// reports and the first and last time each title was seen.
+func AddTitleAliases(titles map[string][]models.TitleAlias) error {
+	appIDs := make([]string, 0, len(titles))
+	var writes []mongo.WriteModel
+	for appID, aliases := range titles {
+		if len(aliases) == 0 {
+			continue
+		}
+		appIDs = append(appIDs, appID)
+		// The writes are ordered: an alias already stored is updated in place, otherwise it is pushed
+		for _, alias := range aliases {
+			writes = append(writes,
+				mongo.NewUpdateOneModel().
+					SetFilter(bson.M{"appId": appID, "titles.title": alias.Title}).
+					SetUpdate(bson.M{
+						"$inc": bson.M{"titles.$.count": alias.Count},
+						"$min": bson.M{"titles.$.firstSeen": alias.FirstSeen},
+						"$max": bson.M{"titles.$.lastSeen": alias.LastSeen},
+					}),
+				mongo.NewUpdateOneModel().
+					SetFilter(bson.M{"appId": appID, "titles.title": bson.M{"$ne": alias.Title}}).
+					SetUpdate(bson.M{"$push": bson.M{"titles": alias}}),
+			)
+		}
+	}
+	if len(writes) == 0 {
+		return nil
+	}
 
-	_, err := gamesCollection.UpdateOne(context.Background(), filter, update)
+	_, err := gamesCollection.BulkWrite(context.Background(), writes, options.BulkWrite().SetOrdered(true))
 	if err != nil {
 		return err
 	}
-	return nil
-}
 
-// search game bby titles
-func SearchGameByTitle(title string, precision float64) (*mongo.Cursor, error) {
-	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: title}}}}}},
-		{{Key: "$addFields", Value: bson.D{{Key: "score", Value: bson.D{{Key: "$meta", Value: "textScore"}}}}}},
-		{{Key: "$match", Value: bson.D{{Key: "score", Value: bson.D{{Key: "$gte", Value: precision}}}}}},
-		{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}}}},
+	cursor, err := gamesCollection.Find(context.Background(), bson.M{"appId": bson.M{"$in": appIDs}}, options.Find().SetProjection(bson.M{"title": 1, "titles": 1}))
+	if err != nil {
+		return err
 	}
+	defer cursor.Close(context.Background())
 
-	cursor, err := gamesCollection.Aggregate(context.Background(), pipeline)
-	return cursor, err
-}
+	var titleWrites []mongo.WriteModel
+	for cursor.Next(context.Background()) {
+		var game models.Game
+		if err := cursor.Decode(&game); err != nil {
+			return err
+		}
+		if canonical := models.CanonicalTitle(game.Titles); canonical != "" && (game.Title == nil || *game.Title != canonical) {
+			titleWrites = append(titleWrites, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": game.ID}).
+				SetUpdate(bson.M{"$set": bson.M{"title": canonical}}))
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
 
-func GetGameByTitle(title string) (*models.Game, error) {
-	filter := bson.M{"title": title}
-	var game models.Game
-	err := gamesCollection.FindOne(context.Background(), filter).Decode(&game)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
+	if len(titleWrites) > 0 {
+		if _, err := gamesCollection.BulkWrite(context.Background(), titleWrites, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
 		}
-		return nil, err
 	}
-	return &game, nil
+	return nil
 }
@@ -0,0 +1,617 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryStore is a Store that keeps everything in process memory.
+// It is meant for tests and local development without a MongoDB instance.
+type MemoryStore struct {
+	mu           sync.RWMutex
+	games        map[primitive.ObjectID]*models.Game
+	gamesByAppID map[string]primitive.ObjectID
+	gameOrder    []primitive.ObjectID
+	reports      map[primitive.ObjectID]*models.Report
+	reportOrder  []primitive.ObjectID
+	reportHashes map[string]primitive.ObjectID
+	// reportsByAppID stands in for the index on the appId field of the reports
+	reportsByAppID map[string][]primitive.ObjectID
+	processStatus  *models.ProcessStatus
+	datasetStats   *models.DatasetStats
+	regressions    []models.Regression
+	regressionRun  *models.RegressionRun
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		games:          make(map[primitive.ObjectID]*models.Game),
+		gamesByAppID:   make(map[string]primitive.ObjectID),
+		reports:        make(map[primitive.ObjectID]*models.Report),
+		reportHashes:   make(map[string]primitive.ObjectID),
+		reportsByAppID: make(map[string][]primitive.ObjectID),
+	}
+}
+
+// copyGame returns a detached copy of the game, without its report list
+func copyGame(game *models.Game) *models.Game {
+	copied := *game
+	if game.Title != nil {
+		title := *game.Title
+		copied.Title = &title
+	}
+	copied.Titles = append([]models.TitleAlias(nil), game.Titles...)
+	copied.Reports = nil
+	return &copied
+}
+
+func (m *MemoryStore) GetGameByAppID(appID string) (*models.Game, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	id, ok := m.gamesByAppID[appID]
+	if !ok {
+		return nil, nil
+	}
+	return copyGame(m.games[id]), nil
+}
+
+func (m *MemoryStore) GetAllGames() ([]models.Game, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var games []models.Game
+	for _, id := range m.gameOrder {
+		games = append(games, *copyGame(m.games[id]))
+	}
+	return games, nil
+}
+
+func (m *MemoryStore) ListGames(after primitive.ObjectID, limit int) ([]models.Game, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := idsAfter(m.gameOrder, after)
+	games := []models.Game{}
+	for _, id := range ids {
+		if len(games) == limit {
+			break
+		}
+		games = append(games, *copyGame(m.games[id]))
+	}
+	return games, nil
+}
+
+func (m *MemoryStore) GetGamesByIDs(ids []primitive.ObjectID) ([]models.Game, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	games := []models.Game{}
+	for _, id := range ids {
+		if game, ok := m.games[id]; ok {
+			games = append(games, *copyGame(game))
+		}
+	}
+	return games, nil
+}
+
+func (m *MemoryStore) GetTotalGamesCount() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.games)), nil
+}
+
+func (m *MemoryStore) UpsertGames(titles map[string]string) (map[string]*models.Game, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	games := make(map[string]*models.Game, len(titles))
+	for appID, title := range titles {
+		id, ok := m.gamesByAppID[appID]
+		if !ok {
+			id = primitive.NewObjectID()
+			title := title
+			m.games[id] = &models.Game{ID: id, AppID: appID, Title: &title, Reports: []primitive.ObjectID{}}
+			m.gamesByAppID[appID] = id
+			m.gameOrder = append(m.gameOrder, id)
+		}
+		games[appID] = copyGame(m.games[id])
+	}
+	return games, nil
+}
+
+func (m *MemoryStore) AddTitleAliases(titles map[string][]models.TitleAlias) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for appID, aliases := range titles {
+		id, ok := m.gamesByAppID[appID]
+		if !ok || len(aliases) == 0 {
+			continue
+		}
+		game := m.games[id]
+		for _, alias := range aliases {
+			game.Titles = models.AddTitleAlias(game.Titles, alias)
+		}
+		canonical := models.CanonicalTitle(game.Titles)
+		game.Title = &canonical
+	}
+	return nil
+}
+
+func (m *MemoryStore) InsertReports(reports []*models.Report) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	failed := make(map[int]error)
+	for i, report := range reports {
+		report.ID = primitive.NewObjectID()
+		if err := m.insertReport(report); err != nil {
+			failed[i] = err
+		}
+	}
+	if len(failed) > 0 {
+		return &BulkWriteError{Failed: failed}
+	}
+	return nil
+}
+
+// insertReport stores a copy of the report, enforcing unique hashes like the Mongo index does
+func (m *MemoryStore) insertReport(report *models.Report) error {
+	if report.Hash != "" {
+		if _, ok := m.reportHashes[report.Hash]; ok {
+			return ErrDuplicateReport
+		}
+		m.reportHashes[report.Hash] = report.ID
+	}
+
+	copied := *report
+	m.reports[report.ID] = &copied
+	m.reportOrder = append(m.reportOrder, report.ID)
+	if report.AppID != "" {
+		m.reportsByAppID[report.AppID] = append(m.reportsByAppID[report.AppID], report.ID)
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetReportsByGameID(gameID string, version string) ([]models.Report, error) {
+	if gameID == "" {
+		return nil, errors.New("empty gameID provided")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	reports := []models.Report{}
+	for _, reportID := range m.reportsByAppID[gameID] {
+		report, ok := m.reports[reportID]
+		if !ok {
+			continue
+		}
+		if (version == "V1" || version == "V2") && report.ReportVersion != version {
+			continue
+		}
+		reports = append(reports, *report)
+	}
+	return reports, nil
+}
+
+func (m *MemoryStore) GetReportRatings(appID string) ([]models.ReportRating, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ratings := []models.ReportRating{}
+	for _, reportID := range m.reportsByAppID[appID] {
+		report, ok := m.reports[reportID]
+		if !ok || report.Normalized == nil {
+			continue
+		}
+		ratings = append(ratings, models.ReportRating{Rating: report.Normalized.Rating, Timestamp: report.Normalized.Timestamp})
+	}
+	return ratings, nil
+}
+
+func (m *MemoryStore) GetReportCounts() (map[string]int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]int64, len(m.reportsByAppID))
+	for appID, ids := range m.reportsByAppID {
+		if len(ids) > 0 {
+			counts[appID] = int64(len(ids))
+		}
+	}
+	return counts, nil
+}
+
+func (m *MemoryStore) GetGameStats(appID string, top int) (*models.GameStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	months, ratings, protonVersions := statCounter{}, statCounter{}, statCounter{}
+	gpuVendors, distros, tweaks, launchOptions := statCounter{}, statCounter{}, statCounter{}, statCounter{}
+	stats := &models.GameStats{AppID: appID}
+	for _, reportID := range m.reportsByAppID[appID] {
+		report, ok := m.reports[reportID]
+		if !ok || report.Normalized == nil {
+			continue
+		}
+		normalized := report.Normalized
+
+		stats.Total++
+		if !normalized.Timestamp.IsZero() {
+			months.add(normalized.Timestamp.UTC().Format("2006-01"))
+		}
+		ratings.add(normalized.Rating)
+		protonVersions.add(normalized.ProtonVersion)
+		gpuVendors.add(normalized.GPUVendor)
+		distros.add(normalized.OS)
+		for _, tweak := range normalized.Tweaks {
+			tweaks.add(tweak)
+		}
+		launchOptions.add(normalized.LaunchOptions)
+	}
+
+	stats.ReportsPerMonth = months.top(0)
+	sort.Slice(stats.ReportsPerMonth, func(i, j int) bool {
+		return stats.ReportsPerMonth[i].Value < stats.ReportsPerMonth[j].Value
+	})
+	stats.Ratings = ratings.top(0)
+	stats.ProtonVersions = protonVersions.top(top)
+	stats.GPUVendors = gpuVendors.top(0)
+	stats.Distros = distros.top(top)
+	stats.Tweaks = tweaks.top(top)
+	stats.LaunchOptions = launchOptions.top(top)
+	return stats, nil
+}
+
+func (m *MemoryStore) GetProtonReleaseCounts(appID string) ([]models.ProtonReleaseCount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	type release struct {
+		build        string
+		major, minor int
+	}
+	counts := make(map[release]*models.ProtonReleaseCount)
+	var order []release
+	for _, reportID := range m.reportsByAppID[appID] {
+		report, ok := m.reports[reportID]
+		if !ok || report.Normalized == nil || report.Normalized.Proton.Build == "" {
+			continue
+		}
+		version := report.Normalized.Proton
+
+		key := release{version.Build, version.Major, version.Minor}
+		count, ok := counts[key]
+		if !ok {
+			count = &models.ProtonReleaseCount{Build: version.Build, Major: version.Major, Minor: version.Minor}
+			counts[key] = count
+			order = append(order, key)
+		}
+		count.Reports++
+		if report.Normalized.RatingRank >= models.RatingRank(models.RatingSilver) {
+			count.Working++
+		}
+	}
+
+	result := make([]models.ProtonReleaseCount, 0, len(order))
+	for _, key := range order {
+		result = append(result, *counts[key])
+	}
+	return result, nil
+}
+
+// ListReports filters and sorts in memory. Fields are ignored, reports are always returned whole.
+func (m *MemoryStore) ListReports(query ReportQuery) ([]models.Report, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var ids []primitive.ObjectID
+	if len(query.AppIDs) > 0 {
+		for _, appID := range query.AppIDs {
+			ids = append(ids, m.reportsByAppID[appID]...)
+		}
+	} else {
+		ids = m.reportOrder
+	}
+
+	var matched []models.Report
+	for _, id := range ids {
+		report, ok := m.reports[id]
+		if !ok {
+			continue
+		}
+		if (query.Version == "V1" || query.Version == "V2") && report.ReportVersion != query.Version {
+			continue
+		}
+		if !query.Filter.matches(report) {
+			continue
+		}
+		matched = append(matched, *report)
+	}
+
+	reports := query.Sort.sortReports(matched, query.After, query.AfterValue)
+	if len(reports) > query.Limit {
+		reports = reports[:query.Limit]
+	}
+	return append([]models.Report{}, reports...), nil
+}
+
+func (m *MemoryStore) GetGamesWithReports(query GamesWithReportsQuery) ([]models.MatchedGame, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	games := []models.MatchedGame{}
+	for _, gameID := range query.GameIDs {
+		game, ok := m.games[gameID]
+		if !ok {
+			continue
+		}
+
+		matched := models.MatchedGame{Game: *copyGame(game), MatchedReports: []models.Report{}}
+		for _, id := range idsAfter(m.reportsByAppID[game.AppID], primitive.NilObjectID) {
+			if query.ReportsPerGame > 0 && len(matched.MatchedReports) == query.ReportsPerGame {
+				break
+			}
+			report, ok := m.reports[id]
+			if !ok || ((query.Version == "V1" || query.Version == "V2") && report.ReportVersion != query.Version) || !query.Filter.matches(report) {
+				continue
+			}
+			matched.MatchedReports = append(matched.MatchedReports, *report)
+		}
+		games = append(games, matched)
+	}
+	return games, nil
+}
+
+func (m *MemoryStore) GetTotalReportsCount() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.reports)), nil
+}
+
+func (m *MemoryStore) ComputeDatasetStats(top int) (*models.DatasetStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	months, games, ratings := statCounter{}, statCounter{}, statCounter{}
+	gpus, distros, protonVersions := statCounter{}, statCounter{}, statCounter{}
+	for _, id := range m.reportOrder {
+		normalized := m.reports[id].Normalized
+		if normalized == nil {
+			continue
+		}
+		if !normalized.Timestamp.IsZero() {
+			months.add(normalized.Timestamp.UTC().Format("2006-01"))
+		}
+		games.add(m.reports[id].AppID)
+		ratings.add(normalized.Rating)
+		gpus.add(normalized.GPU)
+		distros.add(normalized.OS)
+		protonVersions.add(normalized.ProtonVersion)
+	}
+
+	stats := &models.DatasetStats{
+		ComputedAt:        time.Now().UTC(),
+		ReportsPerMonth:   months.top(0),
+		TopGames:          []models.GameCount{},
+		Ratings:           ratings.top(0),
+		TopGPUs:           gpus.top(top),
+		TopDistros:        distros.top(top),
+		TopProtonVersions: protonVersions.top(top),
+	}
+	sort.Slice(stats.ReportsPerMonth, func(i, j int) bool {
+		return stats.ReportsPerMonth[i].Value < stats.ReportsPerMonth[j].Value
+	})
+	for _, game := range games.top(top) {
+		count := models.GameCount{AppID: game.Value, Count: game.Count}
+		if id, ok := m.gamesByAppID[game.Value]; ok && m.games[id].Title != nil {
+			count.Title = *m.games[id].Title
+		}
+		stats.TopGames = append(stats.TopGames, count)
+	}
+	return stats, nil
+}
+
+func (m *MemoryStore) StreamGameRatings(fn func(appID string, ratings []models.ReportRating) error) error {
+	m.mu.RLock()
+	ratingsByAppID := make(map[string][]models.ReportRating, len(m.reportsByAppID))
+	for appID, ids := range m.reportsByAppID {
+		for _, id := range ids {
+			if report, ok := m.reports[id]; ok && report.Normalized != nil {
+				ratingsByAppID[appID] = append(ratingsByAppID[appID], models.ReportRating{Rating: report.Normalized.Rating, Timestamp: report.Normalized.Timestamp})
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	for appID, ratings := range ratingsByAppID {
+		if err := fn(appID, ratings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) SaveDatasetStats(stats *models.DatasetStats) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	copied := *stats
+	m.datasetStats = &copied
+	return nil
+}
+
+func (m *MemoryStore) GetDatasetStats() (*models.DatasetStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.datasetStats == nil {
+		return nil, nil
+	}
+	copied := *m.datasetStats
+	return &copied, nil
+}
+
+func (m *MemoryStore) GetVersionRatings(component string) ([]models.VersionRatings, error) {
+	if _, ok := regressionFields[component]; !ok {
+		return nil, fmt.Errorf("unknown component: %s", component)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	type version struct {
+		appID, group string
+		major, minor int
+	}
+	sums := make(map[version]*models.VersionRatings)
+	var order []version
+	for _, id := range m.reportOrder {
+		report, ok := m.reports[id]
+		if !ok || report.Normalized == nil {
+			continue
+		}
+		group, major, minor, ok := reportVersion(component, report.Normalized)
+		if !ok {
+			continue
+		}
+
+		key := version{report.AppID, group, major, minor}
+		sum, ok := sums[key]
+		if !ok {
+			sum = &models.VersionRatings{AppID: report.AppID, Group: group, Major: major, Minor: minor}
+			sums[key] = sum
+			order = append(order, key)
+		}
+		points := float64(report.Normalized.RatingRank - 1)
+		if points > 4 {
+			points = 4
+		}
+		sum.Reports++
+		sum.Points += points
+		sum.PointsSquared += points * points
+	}
+
+	ratings := make([]models.VersionRatings, 0, len(order))
+	for _, key := range order {
+		ratings = append(ratings, *sums[key])
+	}
+	return ratings, nil
+}
+
+func (m *MemoryStore) SaveRegressions(regressions []models.Regression, run models.RegressionRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	run.RunID = primitive.NewObjectID()
+	m.regressions = make([]models.Regression, len(regressions))
+	for i := range regressions {
+		regressions[i].ID = primitive.NewObjectID()
+		regressions[i].RunID = run.RunID
+		m.regressions[i] = regressions[i]
+	}
+	m.regressionRun = &run
+	return nil
+}
+
+func (m *MemoryStore) GetRegressionRun() (*models.RegressionRun, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.regressionRun == nil {
+		return nil, nil
+	}
+	copied := *m.regressionRun
+	return &copied, nil
+}
+
+func (m *MemoryStore) ListRegressions(query RegressionQuery) ([]models.Regression, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	regressions := []models.Regression{}
+	for _, regression := range m.regressions {
+		if !query.After.IsZero() && bytes.Compare(regression.ID[:], query.After[:]) <= 0 {
+			continue
+		}
+		if !query.matches(regression) {
+			continue
+		}
+		regressions = append(regressions, regression)
+		if query.Limit > 0 && len(regressions) == query.Limit {
+			break
+		}
+	}
+	return regressions, nil
+}
+
+func (m *MemoryStore) GetLastProcessStatus() (*models.ProcessStatus, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.processStatus == nil {
+		return nil, nil
+	}
+	copied := *m.processStatus
+	return &copied, nil
+}
+
+func (m *MemoryStore) GetLastProcessedData() (*models.ProcessStatus, error) {
+	return m.GetLastProcessStatus()
+}
+
+func (m *MemoryStore) CreateProcessStatus(processStatus *models.ProcessStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	processStatus.ID = primitive.NewObjectID()
+	copied := *processStatus
+	m.processStatus = &copied
+	return nil
+}
+
+func (m *MemoryStore) UpdateProcessStatus(processStatus *models.ProcessStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.processStatus == nil || m.processStatus.ID != processStatus.ID {
+		return nil
+	}
+	copied := *processStatus
+	m.processStatus = &copied
+	return nil
+}
+
+func removeID(ids []primitive.ObjectID, id primitive.ObjectID) []primitive.ObjectID {
+	for i, existing := range ids {
+		if existing == id {
+			return append(ids[:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
+
+// idsAfter returns the IDs greater than after in ascending order, the same order Mongo lists by _id
+func idsAfter(ids []primitive.ObjectID, after primitive.ObjectID) []primitive.ObjectID {
+	sorted := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if bytes.Compare(id[:], after[:]) > 0 {
+			sorted = append(sorted, id)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+	return sorted
+}
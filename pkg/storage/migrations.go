@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migration is a change to the stored schema or data, applied once per database.
+// Up must be safe to run again if it fails halfway, as it is retried on the next run.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func() error
+}
+
+// migrations are applied in order of version. Append new migrations with the next version,
+// never renumber or remove applied ones.
+var migrations = []Migration{
+	{1, "create_title_index", ensureTitleIndex},
+	{2, "create_report_hash_index", ensureReportHashIndex},
+	// Hash the reports stored before hashes existed, so the unique index covers them too
+	{3, "backfill_report_hashes", func() error {
+		_, err := BackfillReportHashes()
+		return err
+	}},
+	{4, "create_report_filter_indexes", ensureReportFilterIndexes},
+	{5, "create_report_game_indexes", ensureReportGameIndexes},
+	// Reference the game on the reports stored when only the game listed its reports
+	{6, "backfill_report_game_refs", func() error {
+		_, err := BackfillReportGameRefs()
+		return err
+	}},
+	// Normalize the reports stored before the normalized shape, or some of its fields, existed
+	{7, "backfill_normalized_reports", func() error {
+		_, err := BackfillNormalizedReports()
+		return err
+	}},
+	// Titles are searched in the in-process index of the search package since
+	{8, "drop_title_text_index", dropTitleTextIndex},
+	// Count the titles reports were filed under, for the games stored when only their last title was kept
+	{9, "backfill_game_titles", func() error {
+		_, err := BackfillGameTitles()
+		return err
+	}},
+	{10, "create_report_hardware_indexes", ensureReportHardwareIndexes},
+	// Parse the system info of the reports normalized before it was parsed into structured fields
+	{11, "backfill_report_hardware", func() error {
+		_, err := BackfillReportHardware()
+		return err
+	}},
+	{12, "create_report_proton_indexes", ensureReportProtonIndexes},
+	// Parse the Proton version of the reports normalized before it was parsed
+	{13, "backfill_report_proton_versions", func() error {
+		_, err := BackfillReportProtonVersions()
+		return err
+	}},
+	{14, "create_regression_indexes", ensureRegressionIndexes},
+	// Regressions are listed from the last run since they are saved under the ID of their run
+	{15, "create_regression_run_indexes", ensureRegressionRunIndexes},
+	// Games were looked up and created separately before they were upserted, which could create a game twice
+	{16, "merge_duplicate_games", func() error {
+		_, err := MergeDuplicateGames()
+		return err
+	}},
+	{17, "create_game_app_id_index", ensureGameAppIDIndex},
+}
+
+// AppliedMigration is the record of an applied migration in the schema_migrations collection
+type AppliedMigration struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+	Duration  int64     `bson:"duration_ms"`
+}
+
+const (
+	migrationLockID = "lock"
+	// migrationLockTTL is how long a lock is honored without being refreshed, so a crashed instance
+	// does not block migrations forever. It is refreshed before each migration.
+	migrationLockTTL = 30 * time.Minute
+	// migrationLockWait is how long to wait for another instance to finish migrating
+	migrationLockWait = 2 * time.Hour
+)
+
+var ErrMigrationLocked = errors.New("migrations are locked by another instance")
+
+// PendingMigrations returns the migrations not applied yet, in the order they would run
+func PendingMigrations() ([]Migration, error) {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// RunMigrations applies the pending migrations in order, recording each in schema_migrations.
+// Only one instance migrates at a time, the others wait for the lock and then find nothing left to do.
+// With dryRun the pending migrations are only logged.
+func RunMigrations(dryRun bool) error {
+	if dryRun {
+		pending, err := PendingMigrations()
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			log.Println("No pending migrations")
+		}
+		for _, migration := range pending {
+			log.Printf("Pending migration %d: %s", migration.Version, migration.Name)
+		}
+		return nil
+	}
+
+	owner, err := acquireMigrationLock()
+	if err != nil {
+		return err
+	}
+	defer releaseMigrationLock(owner)
+
+	// Read the applied migrations under the lock, another instance may just have applied some
+	pending, err := PendingMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range pending {
+		if err := refreshMigrationLock(owner); err != nil {
+			return err
+		}
+
+		log.Printf("Applying migration %d: %s", migration.Version, migration.Name)
+		start := time.Now()
+		if err := migration.Up(); err != nil {
+			return fmt.Errorf("migration %d %s failed: %w", migration.Version, migration.Name, err)
+		}
+
+		record := AppliedMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now().UTC(),
+			Duration:  time.Since(start).Milliseconds(),
+		}
+		if _, err := migrationsCollection.InsertOne(context.Background(), record); err != nil {
+			return err
+		}
+		log.Printf("Applied migration %d: %s in %s", migration.Version, migration.Name, time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
+
+func appliedMigrations() (map[int]AppliedMigration, error) {
+	cursor, err := migrationsCollection.Find(context.Background(), bson.M{"_id": bson.M{"$ne": migrationLockID}})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []AppliedMigration
+	if err := cursor.All(context.Background(), &records); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]AppliedMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// acquireMigrationLock takes the lock document, waiting for another instance holding it to release
+// it or let it expire. It returns the owner token to refresh and release the lock with.
+func acquireMigrationLock() (string, error) {
+	hostname, _ := os.Hostname()
+	owner := fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), primitive.NewObjectID().Hex())
+
+	deadline := time.Now().Add(migrationLockWait)
+	for {
+		now := time.Now()
+		// Take the lock if it is free or expired, the unique _id makes a concurrent insert fail
+		filter := bson.M{"_id": migrationLockID, "expires_at": bson.M{"$lt": now}}
+		update := bson.M{"$set": bson.M{"owner": owner, "locked_at": now, "expires_at": now.Add(migrationLockTTL)}}
+		_, err := migrationsCollection.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true))
+		if err == nil {
+			return owner, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return "", err
+		}
+
+		if now.After(deadline) {
+			return "", ErrMigrationLocked
+		}
+		log.Println("Waiting for another instance to finish migrating...")
+		time.Sleep(5 * time.Second)
+	}
+}
+
+func refreshMigrationLock(owner string) error {
+	filter := bson.M{"_id": migrationLockID, "owner": owner}
+	update := bson.M{"$set": bson.M{"expires_at": time.Now().Add(migrationLockTTL)}}
+
+	result, err := migrationsCollection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrMigrationLocked
+	}
+	return nil
+}
+
+func releaseMigrationLock(owner string) {
+	_, err := migrationsCollection.DeleteOne(context.Background(), bson.M{"_id": migrationLockID, "owner": owner})
+	if err != nil {
+		log.Printf("Error releasing the migration lock: %v", err)
+	}
+}
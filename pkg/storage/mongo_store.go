@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MongoStore is the Store backed by the MongoDB collections opened in ConnectDB.
+type MongoStore struct{}
+
+func NewMongoStore() *MongoStore {
+	return &MongoStore{}
+}
+
+func (m *MongoStore) GetGameByAppID(appID string) (*models.Game, error) {
+	return GetGameByAppID(appID)
+}
+
+func (m *MongoStore) GetAllGames() ([]models.Game, error) {
+	cursor, err := GetAllGames()
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var games []models.Game
+	if err := cursor.All(context.Background(), &games); err != nil {
+		return nil, err
+	}
+	return games, nil
+}
+
+func (m *MongoStore) ListGames(after primitive.ObjectID, limit int) ([]models.Game, error) {
+	return ListGames(after, limit)
+}
+
+func (m *MongoStore) GetGamesByIDs(ids []primitive.ObjectID) ([]models.Game, error) {
+	return GetGamesByIDs(ids)
+}
+
+func (m *MongoStore) GetTotalGamesCount() (int64, error) {
+	return GetTotalGamesCount()
+}
+
+func (m *MongoStore) UpsertGames(titles map[string]string) (map[string]*models.Game, error) {
+	return UpsertGames(titles)
+}
+
+func (m *MongoStore) AddTitleAliases(titles map[string][]models.TitleAlias) error {
+	return AddTitleAliases(titles)
+}
+
+func (m *MongoStore) InsertReports(reports []*models.Report) error {
+	return InsertReports(reports)
+}
+
+func (m *MongoStore) GetReportsByGameID(gameID string, version string) ([]models.Report, error) {
+	return GetReportsByGameID(gameID, version)
+}
+
+func (m *MongoStore) GetReportRatings(appID string) ([]models.ReportRating, error) {
+	return GetReportRatings(appID)
+}
+
+func (m *MongoStore) GetReportCounts() (map[string]int64, error) {
+	return GetReportCounts()
+}
+
+func (m *MongoStore) GetGameStats(appID string, top int) (*models.GameStats, error) {
+	return GetGameStats(appID, top)
+}
+
+func (m *MongoStore) GetProtonReleaseCounts(appID string) ([]models.ProtonReleaseCount, error) {
+	return GetProtonReleaseCounts(appID)
+}
+
+func (m *MongoStore) ListReports(query ReportQuery) ([]models.Report, error) {
+	return ListReports(query)
+}
+
+func (m *MongoStore) GetGamesWithReports(query GamesWithReportsQuery) ([]models.MatchedGame, error) {
+	return GetGamesWithReports(query)
+}
+
+func (m *MongoStore) GetTotalReportsCount() (int64, error) {
+	return GetTotalReportsCount()
+}
+
+func (m *MongoStore) ComputeDatasetStats(top int) (*models.DatasetStats, error) {
+	return ComputeDatasetStats(top)
+}
+
+func (m *MongoStore) StreamGameRatings(fn func(appID string, ratings []models.ReportRating) error) error {
+	return StreamGameRatings(fn)
+}
+
+func (m *MongoStore) SaveDatasetStats(stats *models.DatasetStats) error {
+	return SaveDatasetStats(stats)
+}
+
+func (m *MongoStore) GetDatasetStats() (*models.DatasetStats, error) {
+	return GetDatasetStats()
+}
+
+func (m *MongoStore) GetVersionRatings(component string) ([]models.VersionRatings, error) {
+	return GetVersionRatings(component)
+}
+
+func (m *MongoStore) SaveRegressions(regressions []models.Regression, run models.RegressionRun) error {
+	return SaveRegressions(regressions, run)
+}
+
+func (m *MongoStore) GetRegressionRun() (*models.RegressionRun, error) {
+	return GetRegressionRun()
+}
+
+func (m *MongoStore) ListRegressions(query RegressionQuery) ([]models.Regression, error) {
+	return ListRegressions(query)
+}
+
+func (m *MongoStore) GetLastProcessStatus() (*models.ProcessStatus, error) {
+	return GetLastProcessStatus()
+}
+
+func (m *MongoStore) GetLastProcessedData() (*models.ProcessStatus, error) {
+	return GetLastProcessedData()
+}
+
+func (m *MongoStore) CreateProcessStatus(processStatus *models.ProcessStatus) error {
+	return CreateProcessStatus(processStatus)
+}
+
+func (m *MongoStore) UpdateProcessStatus(processStatus *models.ProcessStatus) error {
+	return UpdateProcessStatus(processStatus)
+}
@@ -28,8 +28,11 @@ func UpdateProcessStatus(processStatus *models.ProcessStatus) error {
 	filter := bson.M{"_id": processStatus.ID}
 	update := bson.M{
 		"$set": bson.M{
-			"last_processed_file": processStatus.LastProcessedFile,
-			"last_processed_time": processStatus.LastProcessedTime,
+			"last_processed_file":   processStatus.LastProcessedFile,
+			"last_processed_time":   processStatus.LastProcessedTime,
+			"current_file":          processStatus.CurrentFile,
+			"current_file_checksum": processStatus.CurrentFileChecksum,
+			"last_committed_index":  processStatus.LastCommittedIndex,
 		},
 	}
 
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetProtonReleaseCounts counts the reports of a game per Proton release, and those working among them.
+// Reports without a Proton version are left out.
+func GetProtonReleaseCounts(appID string) ([]models.ProtonReleaseCount, error) {
+	workingRank := models.RatingRank(models.RatingSilver)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"appId": appID, "normalized.proton.build": bson.M{"$nin": bson.A{nil, ""}}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "build", Value: "$normalized.proton.build"},
+				{Key: "major", Value: "$normalized.proton.major"},
+				{Key: "minor", Value: "$normalized.proton.minor"},
+			}},
+			{Key: "reports", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "working", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$gte", Value: bson.A{"$normalized.ratingRank", workingRank}}}, 1, 0,
+			}}}}}},
+		}}},
+	}
+
+	cursor, err := reportsCollection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	counts := []models.ProtonReleaseCount{}
+	for cursor.Next(context.Background()) {
+		var group struct {
+			Release models.ProtonReleaseCount `bson:"_id"`
+			Reports int64                     `bson:"reports"`
+			Working int64                     `bson:"working"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return nil, err
+		}
+		group.Release.Reports = group.Reports
+		group.Release.Working = group.Working
+		counts = append(counts, group.Release)
+	}
+	return counts, cursor.Err()
+}
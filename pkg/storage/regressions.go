@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// regressionRunID is the _id of the record of the last regression detection in the stats collection
+const regressionRunID = "regressions"
+
+// regressionFields are the stored fields holding the group and version of each component.
+// Reports without the version store a code of 0.
+var regressionFields = map[string]struct {
+	group, major, minor, code string
+}{
+	models.RegressionProton: {"normalized.proton.build", "normalized.proton.major", "normalized.proton.minor", "normalized.proton.code"},
+	models.RegressionDriver: {"normalized.hardware.driver.vendor", "normalized.hardware.driver.version.major", "normalized.hardware.driver.version.minor", "normalized.hardware.driver.version.code"},
+	models.RegressionKernel: {"", "normalized.hardware.kernel.major", "normalized.hardware.kernel.minor", "normalized.hardware.kernel.code"},
+}
+
+// GetVersionRatings sums up the ratings of the reports of every game per version of a component,
+// leaving out reports without a rating or a version
+func GetVersionRatings(component string) ([]models.VersionRatings, error) {
+	fields, ok := regressionFields[component]
+	if !ok {
+		return nil, fmt.Errorf("unknown component: %s", component)
+	}
+
+	var group interface{} = ""
+	if fields.group != "" {
+		group = "$" + fields.group
+	}
+	// Ratings are worth points from borked 0 to platinum 4, native counts as platinum
+	points := bson.D{{Key: "$min", Value: bson.A{bson.D{{Key: "$subtract", Value: bson.A{"$normalized.ratingRank", 1}}}, 4}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{fields.code: bson.M{"$gt": 0}, "normalized.ratingRank": bson.M{"$gt": 0}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "appId", Value: "$appId"},
+				{Key: "group", Value: group},
+				{Key: "major", Value: "$" + fields.major},
+				{Key: "minor", Value: "$" + fields.minor},
+			}},
+			{Key: "reports", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "points", Value: bson.D{{Key: "$sum", Value: points}}},
+			{Key: "pointsSquared", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$multiply", Value: bson.A{points, points}}}}}},
+		}}},
+	}
+
+	cursor, err := reportsCollection.Aggregate(context.Background(), pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	ratings := []models.VersionRatings{}
+	for cursor.Next(context.Background()) {
+		var result struct {
+			Version       models.VersionRatings `bson:"_id"`
+			Reports       int64                 `bson:"reports"`
+			Points        float64               `bson:"points"`
+			PointsSquared float64               `bson:"pointsSquared"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+		result.Version.Reports = result.Reports
+		result.Version.Points = result.Points
+		result.Version.PointsSquared = result.PointsSquared
+		ratings = append(ratings, result.Version)
+	}
+	return ratings, cursor.Err()
+}
+
+// SaveRegressions replaces the stored regressions, keeping their order as the _id order, and records the run.
+// The regressions are inserted under a new run ID and the run record is switched to it afterwards, so the
+// previous run is listed until the new one is complete; the regressions of previous runs are deleted last.
+func SaveRegressions(regressions []models.Regression, run models.RegressionRun) error {
+	ctx := context.Background()
+	run.RunID = primitive.NewObjectID()
+
+	if len(regressions) > 0 {
+		documents := make([]interface{}, 0, len(regressions))
+		for i := range regressions {
+			regressions[i].ID = primitive.NewObjectID()
+			regressions[i].RunID = run.RunID
+			documents = append(documents, regressions[i])
+		}
+		if _, err := regressionsCollection.InsertMany(ctx, documents); err != nil {
+			return err
+		}
+	}
+
+	if _, err := statsCollection.ReplaceOne(ctx, bson.M{"_id": regressionRunID}, run, options.Replace().SetUpsert(true)); err != nil {
+		return err
+	}
+
+	// Left over regressions are not listed, and are deleted by the next run if this fails
+	_, err := regressionsCollection.DeleteMany(ctx, bson.M{"runId": bson.M{"$ne": run.RunID}})
+	return err
+}
+
+// GetRegressionRun returns the record of the last regression detection, nil if regressions were never detected
+func GetRegressionRun() (*models.RegressionRun, error) {
+	var run models.RegressionRun
+	err := statsCollection.FindOne(context.Background(), bson.M{"_id": regressionRunID}).Decode(&run)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+// RegressionQuery selects a page of the stored regressions, in the order they were saved.
+// Zero fields do not filter.
+type RegressionQuery struct {
+	AppID     string
+	Component string
+	Group     string
+	// MinReports applies to the reports on both versions
+	MinReports int64
+	MinDrop    float64
+	MaxPValue  float64
+	// After is the _id of the last regression of the previous page, zero for the first page
+	After primitive.ObjectID
+	// Limit is the page size, all regressions are returned when 0
+	Limit int
+}
+
+// ListRegressions returns the regressions of the last run matching the query
+func ListRegressions(query RegressionQuery) ([]models.Regression, error) {
+	run, err := GetRegressionRun()
+	if err != nil {
+		return nil, err
+	}
+	if run == nil {
+		return []models.Regression{}, nil
+	}
+
+	filter := bson.M{}
+	// The regressions stored before runs had an ID are the only ones stored
+	if !run.RunID.IsZero() {
+		filter["runId"] = run.RunID
+	}
+	if query.AppID != "" {
+		filter["appId"] = query.AppID
+	}
+	if query.Component != "" {
+		filter["component"] = query.Component
+	}
+	if query.Group != "" {
+		filter["group"] = query.Group
+	}
+	if query.MinReports > 0 {
+		filter["fromReports"] = bson.M{"$gte": query.MinReports}
+		filter["toReports"] = bson.M{"$gte": query.MinReports}
+	}
+	if query.MinDrop > 0 {
+		filter["drop"] = bson.M{"$gte": query.MinDrop}
+	}
+	if query.MaxPValue > 0 {
+		filter["pValue"] = bson.M{"$lte": query.MaxPValue}
+	}
+	if !query.After.IsZero() {
+		filter["_id"] = bson.M{"$gt": query.After}
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(query.Limit))
+	cursor, err := regressionsCollection.Find(context.Background(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	regressions := []models.Regression{}
+	if err := cursor.All(context.Background(), &regressions); err != nil {
+		return nil, err
+	}
+	return regressions, nil
+}
+
+// matches applies the query filters to a regression the same way ListRegressions does in the database
+func (q RegressionQuery) matches(regression models.Regression) bool {
+	return (q.AppID == "" || regression.AppID == q.AppID) &&
+		(q.Component == "" || regression.Component == q.Component) &&
+		(q.Group == "" || regression.Group == q.Group) &&
+		(q.MinReports <= 0 || (regression.FromReports >= q.MinReports && regression.ToReports >= q.MinReports)) &&
+		(q.MinDrop <= 0 || regression.Drop >= q.MinDrop) &&
+		(q.MaxPValue <= 0 || regression.PValue <= q.MaxPValue)
+}
+
+// reportVersion returns the group and version of a component on a report, ok is false if the report
+// has no rating or no version of the component
+func reportVersion(component string, normalized *models.NormalizedReport) (group string, major int, minor int, ok bool) {
+	if normalized.RatingRank <= 0 {
+		return "", 0, 0, false
+	}
+	switch component {
+	case models.RegressionProton:
+		version := normalized.Proton
+		return version.Build, version.Major, version.Minor, version.Code > 0
+	case models.RegressionDriver:
+		driver := normalized.Hardware.Driver
+		if driver.Version == nil || driver.Version.Code <= 0 {
+			return "", 0, 0, false
+		}
+		return driver.Vendor, driver.Version.Major, driver.Version.Minor, true
+	case models.RegressionKernel:
+		kernel := normalized.Hardware.Kernel
+		if kernel == nil || kernel.Code <= 0 {
+			return "", 0, 0, false
+		}
+		return "", kernel.Major, kernel.Minor, true
+	}
+	return "", 0, 0, false
+}
@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReportFilter narrows a report query down on the normalized fields of the reports.
+// Zero fields do not filter. Text fields match case-insensitively anywhere in the value.
+type ReportFilter struct {
+	GPUVendor     string
+	GPU           string
+	GPUDriver     string
+	OS            string
+	ProtonVersion string
+	// KernelMin and KernelMax are inclusive bounds in the encoding of hardware.Version codes
+	KernelMin int64
+	KernelMax int64
+	// The structured hardware fields, see the hardware package. Vendors and the distro name match
+	// exactly, families and models anywhere in the value. The distro version is set with SetDistroVersion.
+	GPUFamily    string
+	GPUModel     string
+	DriverVendor string
+	DriverMin    int64
+	DriverMax    int64
+	DistroName   string
+	CPUVendor    string
+	CPUModel     string
+	// RAMMin and RAMMax are inclusive bounds in GB
+	RAMMin int
+	RAMMax int
+	// ProtonMin and ProtonMax are inclusive bounds in the encoding of proton.Version codes
+	ProtonMin int64
+	ProtonMax int64
+	// ProtonBuilds matches any of the builds
+	ProtonBuilds []string
+	// Ratings matches any of the ratings
+	Ratings []string
+	Verdict string
+	// From and To are inclusive bounds on the report timestamp
+	From time.Time
+	To   time.Time
+
+	// distroVersion matches the distro version set with SetDistroVersion, compiled once per query
+	distroVersion *regexp.Regexp
+}
+
+var distroVersionValue = regexp.MustCompile(`^\d+(\.\d+)*$`)
+
+// SetDistroVersion filters on a distro release and its point releases, 22.04 matches 22.04 and 22.04.3.
+// The version must be numbers separated by dots, as the hardware package parses distro versions.
+func (f *ReportFilter) SetDistroVersion(version string) error {
+	if !distroVersionValue.MatchString(version) {
+		return fmt.Errorf("invalid distro version: %s, must be a version like 22.04", version)
+	}
+	pattern, err := regexp.Compile("^" + regexp.QuoteMeta(version) + `(\.|$)`)
+	if err != nil {
+		return err
+	}
+	f.distroVersion = pattern
+	return nil
+}
+
+// mongoFilter adds the conditions of the filter to a Mongo filter document
+func (f ReportFilter) mongoFilter(filter bson.M) {
+	contains := func(field, value string) {
+		if value != "" {
+			filter[field] = primitive.Regex{Pattern: regexp.QuoteMeta(value), Options: "i"}
+		}
+	}
+	contains("normalized.gpu", f.GPU)
+	contains("normalized.gpuDriver", f.GPUDriver)
+	contains("normalized.os", f.OS)
+	contains("normalized.protonVersion", f.ProtonVersion)
+	contains("normalized.hardware.gpu.family", f.GPUFamily)
+	contains("normalized.hardware.gpu.model", f.GPUModel)
+	contains("normalized.hardware.cpu.model", f.CPUModel)
+
+	equals := func(field, value string) {
+		if value != "" {
+			filter[field] = strings.ToLower(value)
+		}
+	}
+	equals("normalized.gpuVendor", f.GPUVendor)
+	equals("normalized.hardware.driver.vendor", f.DriverVendor)
+	equals("normalized.hardware.distro.name", f.DistroName)
+	equals("normalized.hardware.cpu.vendor", f.CPUVendor)
+	if f.distroVersion != nil {
+		filter["normalized.hardware.distro.version"] = primitive.Regex{Pattern: f.distroVersion.String()}
+	}
+	if f.Verdict != "" {
+		filter["normalized.verdict"] = strings.ToLower(f.Verdict)
+	}
+	if len(f.Ratings) > 0 {
+		filter["normalized.rating"] = bson.M{"$in": f.Ratings}
+	}
+	if len(f.ProtonBuilds) > 0 {
+		filter["normalized.proton.build"] = bson.M{"$in": f.ProtonBuilds}
+	}
+
+	between := func(field string, min, max int64) {
+		if min <= 0 && max <= 0 {
+			return
+		}
+		// Reports without the value store 0, they are left out of any range
+		bounds := bson.M{"$gt": 0}
+		if min > 0 {
+			bounds = bson.M{"$gte": min}
+		}
+		if max > 0 {
+			bounds["$lte"] = max
+		}
+		filter[field] = bounds
+	}
+	between("normalized.kernelVersion", f.KernelMin, f.KernelMax)
+	between("normalized.hardware.driver.version.code", f.DriverMin, f.DriverMax)
+	between("normalized.hardware.ramGb", int64(f.RAMMin), int64(f.RAMMax))
+	between("normalized.proton.code", f.ProtonMin, f.ProtonMax)
+
+	if !f.From.IsZero() || !f.To.IsZero() {
+		timestamp := bson.M{}
+		if !f.From.IsZero() {
+			timestamp["$gte"] = f.From
+		}
+		if !f.To.IsZero() {
+			timestamp["$lte"] = f.To
+		}
+		filter["normalized.timestamp"] = timestamp
+	}
+}
+
+// matches applies the filter to a report the same way mongoFilter does in the database
+func (f ReportFilter) matches(report *models.Report) bool {
+	normalized := report.Normalized
+	if normalized == nil {
+		return f.isEmpty()
+	}
+	hw := normalized.Hardware
+
+	contains := func(value, substring string) bool {
+		return substring == "" || strings.Contains(strings.ToLower(value), strings.ToLower(substring))
+	}
+	if !contains(normalized.GPU, f.GPU) ||
+		!contains(normalized.GPUDriver, f.GPUDriver) ||
+		!contains(normalized.OS, f.OS) ||
+		!contains(normalized.ProtonVersion, f.ProtonVersion) ||
+		!contains(hw.GPU.Family, f.GPUFamily) ||
+		!contains(hw.GPU.Model, f.GPUModel) ||
+		!contains(hw.CPU.Model, f.CPUModel) {
+		return false
+	}
+
+	equals := func(value, expected string) bool {
+		return expected == "" || value == strings.ToLower(expected)
+	}
+	if !equals(normalized.GPUVendor, f.GPUVendor) ||
+		!equals(hw.Driver.Vendor, f.DriverVendor) ||
+		!equals(hw.Distro.Name, f.DistroName) ||
+		!equals(hw.CPU.Vendor, f.CPUVendor) {
+		return false
+	}
+	if f.distroVersion != nil && !f.distroVersion.MatchString(hw.Distro.Version) {
+		return false
+	}
+	if f.Verdict != "" && normalized.Verdict != strings.ToLower(f.Verdict) {
+		return false
+	}
+	if !isAnyOf(normalized.Rating, f.Ratings) || !isAnyOf(normalized.Proton.Build, f.ProtonBuilds) {
+		return false
+	}
+
+	between := func(value, min, max int64) bool {
+		if min <= 0 && max <= 0 {
+			return true
+		}
+		return value > 0 && value >= min && (max <= 0 || value <= max)
+	}
+	var driverVersion int64
+	if hw.Driver.Version != nil {
+		driverVersion = hw.Driver.Version.Code
+	}
+	if !between(normalized.KernelVersion, f.KernelMin, f.KernelMax) ||
+		!between(driverVersion, f.DriverMin, f.DriverMax) ||
+		!between(int64(hw.RAMGB), int64(f.RAMMin), int64(f.RAMMax)) ||
+		!between(normalized.Proton.Code, f.ProtonMin, f.ProtonMax) {
+		return false
+	}
+
+	if !f.From.IsZero() && normalized.Timestamp.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && normalized.Timestamp.After(f.To) {
+		return false
+	}
+
+	return true
+}
+
+func (f ReportFilter) isEmpty() bool {
+	return f.GPUVendor == "" && f.GPU == "" && f.GPUDriver == "" && f.OS == "" && f.ProtonVersion == "" &&
+		f.KernelMin == 0 && f.KernelMax == 0 && len(f.Ratings) == 0 && f.Verdict == "" &&
+		f.From.IsZero() && f.To.IsZero() &&
+		f.GPUFamily == "" && f.GPUModel == "" && f.DriverVendor == "" && f.DriverMin == 0 && f.DriverMax == 0 &&
+		f.DistroName == "" && f.distroVersion == nil && f.CPUVendor == "" && f.CPUModel == "" &&
+		f.RAMMin == 0 && f.RAMMax == 0 && f.ProtonMin == 0 && f.ProtonMax == 0 && len(f.ProtonBuilds) == 0
+}
+
+// isAnyOf tells if value is one of values, any value is when there are none
+func isAnyOf(value string, values []string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, candidate := range values {
+		if value == candidate {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// reportSortFields maps the fields reports can be sorted on to the stored field holding their sort value.
+// Proton versions sort on their parsed code, so 10.0 comes after 9.0 and GE builds next to the release they are based on.
+var reportSortFields = map[string]string{
+	"timestamp":     "normalized.timestamp",
+	"rating":        "normalized.ratingRank",
+	"protonVersion": "normalized.proton.code",
+}
+
+// ReportSort orders a report query on a normalized field. Reports with the same value, and all reports
+// when Field is empty, are ordered by _id in the same direction.
+type ReportSort struct {
+	Field string
+	Desc  bool
+}
+
+// ParseReportSort reads a sort parameter: a field, optionally prefixed with - or suffixed with :asc or :desc
+func ParseReportSort(value string) (ReportSort, error) {
+	var reportSort ReportSort
+	if strings.HasPrefix(value, "-") {
+		reportSort.Desc = true
+		value = value[1:]
+	}
+	if field, direction, found := strings.Cut(value, ":"); found {
+		switch strings.ToLower(direction) {
+		case "asc":
+		case "desc":
+			reportSort.Desc = true
+		default:
+			return reportSort, fmt.Errorf("invalid sort direction: %s, must be asc or desc", direction)
+		}
+		value = field
+	}
+
+	for field := range reportSortFields {
+		if strings.EqualFold(field, value) {
+			reportSort.Field = field
+			return reportSort, nil
+		}
+	}
+	return reportSort, fmt.Errorf("invalid sort field: %s, must be timestamp, rating or protonVersion", value)
+}
+
+// String returns the sort in the form ParseReportSort reads
+func (s ReportSort) String() string {
+	if s.Field == "" {
+		return ""
+	}
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// Value returns the sort value of a report, nil if the report has none
+func (s ReportSort) Value(report models.Report) interface{} {
+	normalized := report.Normalized
+	if normalized == nil {
+		return nil
+	}
+
+	switch s.Field {
+	case "timestamp":
+		return normalized.Timestamp
+	case "rating":
+		return normalized.RatingRank
+	case "protonVersion":
+		// Versions that could not be parsed, or were not given, store a code of 0
+		return normalized.Proton.Code
+	}
+	return nil
+}
+
+// DecodeValue reads back a sort value that was encoded as JSON
+func (s ReportSort) DecodeValue(raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var err error
+	switch s.Field {
+	case "timestamp":
+		var value time.Time
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case "rating":
+		var value int
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case "protonVersion":
+		var value int64
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	}
+	return nil, fmt.Errorf("cannot decode a value of sort field %q", s.Field)
+}
+
+// mongoSort returns the sort document of the query
+func (s ReportSort) mongoSort() bson.D {
+	direction := 1
+	if s.Desc {
+		direction = -1
+	}
+	if s.Field == "" {
+		return bson.D{{Key: "_id", Value: direction}}
+	}
+	return bson.D{{Key: reportSortFields[s.Field], Value: direction}, {Key: "_id", Value: direction}}
+}
+
+// mongoAfter returns the condition selecting the reports that come after the report with the
+// given _id and sort value. Missing values sort before all others, as they do in Mongo.
+func (s ReportSort) mongoAfter(afterID primitive.ObjectID, afterValue interface{}) bson.M {
+	next, idNext := "$gt", bson.M{"$gt": afterID}
+	if s.Desc {
+		next, idNext = "$lt", bson.M{"$lt": afterID}
+	}
+	if s.Field == "" {
+		return bson.M{"_id": idNext}
+	}
+
+	field := reportSortFields[s.Field]
+	switch {
+	case afterValue == nil && s.Desc:
+		return bson.M{field: nil, "_id": idNext}
+	case afterValue == nil:
+		return bson.M{"$or": bson.A{
+			bson.M{field: nil, "_id": idNext},
+			bson.M{field: bson.M{"$ne": nil}},
+		}}
+	case s.Desc:
+		return bson.M{"$or": bson.A{
+			bson.M{field: bson.M{next: afterValue}},
+			bson.M{field: afterValue, "_id": idNext},
+			bson.M{field: nil},
+		}}
+	default:
+		return bson.M{"$or": bson.A{
+			bson.M{field: bson.M{next: afterValue}},
+			bson.M{field: afterValue, "_id": idNext},
+		}}
+	}
+}
+
+// compare orders two reports the way mongoSort does
+func (s ReportSort) compare(a, b models.Report) int {
+	result := 0
+	if s.Field != "" {
+		result = compareSortValues(s.Value(a), s.Value(b))
+	}
+	if result == 0 {
+		result = bytes.Compare(a.ID[:], b.ID[:])
+	}
+	if s.Desc {
+		return -result
+	}
+	return result
+}
+
+// sortReports sorts reports the way mongoSort does and drops those up to the given _id and sort value
+func (s ReportSort) sortReports(reports []models.Report, afterID primitive.ObjectID, afterValue interface{}) []models.Report {
+	sort.SliceStable(reports, func(i, j int) bool {
+		return s.compare(reports[i], reports[j]) < 0
+	})
+	if afterID.IsZero() {
+		return reports
+	}
+
+	start := sort.Search(len(reports), func(i int) bool {
+		result := compareSortValues(s.Value(reports[i]), afterValue)
+		if result == 0 {
+			result = bytes.Compare(reports[i].ID[:], afterID[:])
+		}
+		if s.Desc {
+			result = -result
+		}
+		return result > 0
+	})
+	return reports[start:]
+}
+
+// compareSortValues compares two sort values of the same field, nil being the smallest
+func compareSortValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	switch a := a.(type) {
+	case time.Time:
+		bTime := b.(time.Time)
+		switch {
+		case a.Before(bTime):
+			return -1
+		case a.After(bTime):
+			return 1
+		}
+		return 0
+	case int:
+		bInt := b.(int)
+		switch {
+		case a < bInt:
+			return -1
+		case a > bInt:
+			return 1
+		}
+		return 0
+	case int64:
+		bInt := b.(int64)
+		switch {
+		case a < bInt:
+			return -1
+		case a > bInt:
+			return 1
+		}
+		return 0
+	}
+	return 0
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/trsnaqe/protondb-api/pkg/models"
@@ -14,75 +15,13 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func CreateReport(report *models.Report, game *models.Game) (*models.Report, error) {
-	result, err := reportsCollection.InsertOne(context.Background(), report)
-	if err != nil {
-		return nil, err
-	}
-
-	report.ID = result.InsertedID.(primitive.ObjectID)
-
-	return report, nil
-}
-
-func GetReportByID(reportID string) (*models.Report, error) {
-	objectID, err := primitive.ObjectIDFromHex(reportID)
-	if err != nil {
-		return nil, err
-	}
-
-	filter := bson.M{"_id": objectID}
-
-	var report models.Report
-	err = reportsCollection.FindOne(context.Background(), filter).Decode(&report)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
-		}
-		return nil, err
-	}
-
-	return &report, nil
-}
-
-func GetAllReports() (*mongo.Cursor, error) {
-	cursor, err := reportsCollection.Find(context.Background(), bson.M{})
-	if err != nil {
-		return nil, err
-	}
-	return cursor, nil
-}
-
-func UpdateReport(report *models.Report) error {
-	if report == nil {
-		return errors.New("nil report provided")
-	}
-
-	filter := bson.M{"_id": report.ID}
-	update := bson.M{"$set": bson.M{"data": report.Data, "report_version": report.ReportVersion}}
-
-	_, err := reportsCollection.UpdateOne(context.Background(), filter, update)
-	return err
-}
-
 func GetReportsByGameID(gameID string, version string) ([]models.Report, error) {
 	if gameID == "" {
 		log.Println("Error: empty gameID provided")
 		return nil, errors.New("empty gameID provided")
 	}
 
-	game, err := GetGameByAppIDWithReports(gameID)
-	if err != nil {
-		log.Println("Error getting game by AppID:", err)
-		return nil, err
-	}
-
-	if game == nil || game.Reports == nil || len(game.Reports) == 0 {
-		log.Println("No reports found for gameID:", gameID)
-		return []models.Report{}, nil
-	}
-
-	reportsFilter := bson.M{"_id": bson.M{"$in": game.Reports}}
+	reportsFilter := bson.M{"appId": gameID}
 
 	if version == "V1" || version == "V2" {
 		reportsFilter["report_version"] = version
@@ -117,17 +56,6 @@ func GetReportsByGameID(gameID string, version string) ([]models.Report, error)
 	return reports, nil
 }
 
-func DeleteReport(reportID string) error {
-	objectID, err := primitive.ObjectIDFromHex(reportID)
-	if err != nil {
-		return err
-	}
-
-	filter := bson.M{"_id": objectID}
-	_, err = reportsCollection.DeleteOne(context.Background(), filter)
-	return err
-}
-
 // GetTotalReportsCount returns the total number of reports
 func GetTotalReportsCount() (int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -141,85 +69,219 @@ func GetTotalReportsCount() (int64, error) {
 
 	return count, nil
 }
-func CompareReport(report models.ReportFormatV2) bool {
 
-	filter := bson.D{
-		{Key: "data.app.steam.appId", Value: report.App.Steam.AppID},
-		{Key: "data.app.title", Value: report.App.Title},
-		{Key: "data.timestamp", Value: report.Timestamp},
-		{Key: "data.systemInfo.cpu", Value: report.SystemInfo.CPU},
-		{Key: "data.systemInfo.gpu", Value: report.SystemInfo.GPU},
-		{Key: "data.systemInfo.gpuDriver", Value: report.SystemInfo.GPUDriver},
-		{Key: "data.systemInfo.kernel", Value: report.SystemInfo.Kernel},
-		{Key: "data.systemInfo.os", Value: report.SystemInfo.OS},
-		{Key: "data.systemInfo.ram", Value: report.SystemInfo.RAM},
+// GamesWithReportsQuery selects games by _id, with some of their reports
+type GamesWithReportsQuery struct {
+	GameIDs []primitive.ObjectID
+	Version string
+	Filter  ReportFilter
+	// ReportsPerGame caps the reports returned with each game, the first ones stored are returned.
+	// Every report is returned when it is 0.
+	ReportsPerGame int
+}
+
+// GetGamesWithReports returns the games with the given IDs, in no particular order, and joins up to
+// query.ReportsPerGame reports to each in a single aggregation
+func GetGamesWithReports(query GamesWithReportsQuery) ([]models.MatchedGame, error) {
+	reportsPipeline := mongo.Pipeline{}
+	match := bson.M{}
+	if query.Version == "V1" || query.Version == "V2" {
+		match["report_version"] = query.Version
+	}
+	query.Filter.mongoFilter(match)
+	if len(match) > 0 {
+		reportsPipeline = append(reportsPipeline, bson.D{{Key: "$match", Value: match}})
+	}
+	reportsPipeline = append(reportsPipeline, bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}})
+	// $limit must be positive
+	if query.ReportsPerGame > 0 {
+		reportsPipeline = append(reportsPipeline, bson.D{{Key: "$limit", Value: query.ReportsPerGame}})
 	}
 
-	var result models.ReportFormatV2
-	err := reportsCollection.FindOne(context.Background(), filter).Decode(&result)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": bson.M{"$in": query.GameIDs}}}},
+		// A $lookup with both localField and pipeline needs MongoDB 5.0, see the README
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: reportsCollection.Name()},
+			{Key: "localField", Value: "appId"},
+			{Key: "foreignField", Value: "appId"},
+			{Key: "pipeline", Value: reportsPipeline},
+			{Key: "as", Value: "matchedReports"},
+		}}},
+		{{Key: "$project", Value: bson.M{"reports": 0}}},
+	}
 
+	cursor, err := gamesCollection.Aggregate(context.Background(), pipeline)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return false
+		return nil, err
+	}
+
+	games := []models.MatchedGame{}
+	if err := cursor.All(context.Background(), &games); err != nil {
+		return nil, err
+	}
+	return games, nil
+}
+
+// ErrDuplicateReport is returned for reports whose hash is already stored
+var ErrDuplicateReport = errors.New("duplicate report")
+
+// BulkWriteError lists the documents of a bulk write that could not be written, keyed by their index
+type BulkWriteError struct {
+	Failed map[int]error
+}
+
+func (e *BulkWriteError) Error() string {
+	return fmt.Sprintf("%d documents could not be written", len(e.Failed))
+}
+
+// InsertReports inserts the reports with a single unordered InsertMany. IDs are assigned to every report
+// up front; if some documents fail the returned error is a *BulkWriteError and the others are still inserted.
+func InsertReports(reports []*models.Report) error {
+	if len(reports) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, 0, len(reports))
+	for _, report := range reports {
+		report.ID = primitive.NewObjectID()
+		documents = append(documents, report)
+	}
+
+	_, err := reportsCollection.InsertMany(context.Background(), documents, options.InsertMany().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+		failed := make(map[int]error, len(bulkErr.WriteErrors))
+		for _, writeErr := range bulkErr.WriteErrors {
+			if mongo.IsDuplicateKeyError(writeErr) {
+				failed[writeErr.Index] = ErrDuplicateReport
+			} else {
+				failed[writeErr.Index] = writeErr
+			}
 		}
-		log.Printf("Could not perform find operation: %v", err)
-		return false
+		return &BulkWriteError{Failed: failed}
 	}
-	return true
+	return err
 }
-func CountV2Reports() (int64, error) {
-	filter := bson.D{{Key: "report_version", Value: "V2"}}
-	count, err := reportsCollection.CountDocuments(context.Background(), filter)
+
+// GetReportRatings returns the normalized rating and timestamp of every report of a game
+func GetReportRatings(appID string) ([]models.ReportRating, error) {
+	filter := bson.M{"appId": appID, "normalized": bson.M{"$exists": true}}
+	findOptions := options.Find().SetProjection(bson.M{"normalized.rating": 1, "normalized.timestamp": 1})
+
+	cursor, err := reportsCollection.Find(context.Background(), filter, findOptions)
 	if err != nil {
-		log.Printf("Error while counting V2 reports: %v", err)
-		return 0, err
+		return nil, err
 	}
-	return count, nil
+	defer cursor.Close(context.Background())
+
+	ratings := []models.ReportRating{}
+	for cursor.Next(context.Background()) {
+		var report struct {
+			Normalized models.ReportRating `bson:"normalized"`
+		}
+		if err := cursor.Decode(&report); err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, report.Normalized)
+	}
+	return ratings, cursor.Err()
 }
 
-// search game by title and get its reports
-func GetReportsOfMatchedGamesByTitle(title string, versioned bool, version string, precision float64) (*mongo.Cursor, error) {
+// GetReportCounts returns the number of reports of each game, keyed by app ID
+func GetReportCounts() (map[string]int64, error) {
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: title}}}}}},
-		{{Key: "$addFields", Value: bson.D{{Key: "score", Value: bson.D{{Key: "$meta", Value: "textScore"}}}}}},
-		{{Key: "$match", Value: bson.D{{Key: "score", Value: bson.D{{Key: "$gte", Value: precision}}}}}},
-		{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}}}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$appId"}, {Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}}},
 	}
 
-	cursor, err := gamesCollection.Aggregate(context.Background(), pipeline)
+	cursor, err := reportsCollection.Aggregate(context.Background(), pipeline)
 	if err != nil {
 		return nil, err
 	}
+	defer cursor.Close(context.Background())
 
-	var matchedGames []models.Game
+	counts := make(map[string]int64)
 	for cursor.Next(context.Background()) {
-		var game models.Game
-		if err := cursor.Decode(&game); err != nil {
-			log.Printf("Error decoding game: %v", err)
-			continue
+		var group struct {
+			AppID string `bson:"_id"`
+			Count int64  `bson:"count"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return nil, err
 		}
-		matchedGames = append(matchedGames, game)
+		counts[group.AppID] = group.Count
 	}
+	return counts, cursor.Err()
+}
 
-	cursor.Close(context.Background())
+// ReportQuery selects a page of reports, in _id order unless sorted otherwise
+type ReportQuery struct {
+	// AppIDs limits the page to the reports of these games, all reports are listed when empty
+	AppIDs  []string
+	Version string
+	Filter  ReportFilter
+	Sort    ReportSort
+	// After and AfterValue are the _id and sort value of the last report of the previous page,
+	// After is zero for the first page
+	After      primitive.ObjectID
+	AfterValue interface{}
+	// Fields are the stored fields to return, whole reports are returned when empty
+	Fields []string
+	Limit  int
+}
 
-	var gameIDs []primitive.ObjectID
-	for _, game := range matchedGames {
-		gameIDs = append(gameIDs, game.ID)
+// ListReports returns up to query.Limit reports matching the query
+func ListReports(query ReportQuery) ([]models.Report, error) {
+	filter := bson.M{}
+	if len(query.AppIDs) > 0 {
+		filter["appId"] = bson.M{"$in": query.AppIDs}
 	}
-
-	reportsFilter := bson.M{"_id": bson.M{"$in": gameIDs}}
-
-	if version == "V1" || version == "V2" {
-		reportsFilter["report_version"] = version
+	if !query.After.IsZero() {
+		filter["$and"] = bson.A{query.Sort.mongoAfter(query.After, query.AfterValue)}
+	}
+	if query.Version == "V1" || query.Version == "V2" {
+		filter["report_version"] = query.Version
+	}
+	query.Filter.mongoFilter(filter)
+
+	findOptions := options.Find().SetSort(query.Sort.mongoSort()).SetLimit(int64(query.Limit))
+	if len(query.Fields) > 0 {
+		fields := append([]string{}, query.Fields...)
+		if query.Sort.Field != "" {
+			// The sort value is needed to build the cursor of the next page
+			fields = append(fields, reportSortFields[query.Sort.Field])
+		}
+		findOptions.SetProjection(projectionOf(fields))
 	}
 
-	findOptions := options.Find().SetProjection(bson.M{"reports": 1})
-
-	reportsCursor, err := reportsCollection.Find(context.Background(), reportsFilter, findOptions)
+	cursor, err := reportsCollection.Find(context.Background(), filter, findOptions)
 	if err != nil {
+		log.Println("Error finding reports:", err)
+		return nil, err
+	}
+
+	reports := []models.Report{}
+	if err := cursor.All(context.Background(), &reports); err != nil {
+		log.Println("Error decoding reports:", err)
 		return nil, err
 	}
+	return reports, nil
+}
 
-	return reportsCursor, nil
+// projectionOf includes the given fields, skipping those inside another given field which Mongo rejects as a path collision
+func projectionOf(fields []string) bson.M {
+	projection := bson.M{}
+	for _, field := range fields {
+		covered := false
+		for _, other := range fields {
+			if field != other && strings.HasPrefix(field, other+".") {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			projection[field] = 1
+		}
+	}
+	return projection
 }
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Store is the persistence layer used by the services, controllers and the ingestion pipeline.
+type Store interface {
+	// games
+	GetGameByAppID(appID string) (*models.Game, error)
+	GetAllGames() ([]models.Game, error)
+	ListGames(after primitive.ObjectID, limit int) ([]models.Game, error)
+	GetGamesByIDs(ids []primitive.ObjectID) ([]models.Game, error)
+	GetTotalGamesCount() (int64, error)
+	UpsertGames(titles map[string]string) (map[string]*models.Game, error)
+	AddTitleAliases(titles map[string][]models.TitleAlias) error
+
+	// reports
+	InsertReports(reports []*models.Report) error
+	GetReportsByGameID(gameID string, version string) ([]models.Report, error)
+	GetReportRatings(appID string) ([]models.ReportRating, error)
+	GetReportCounts() (map[string]int64, error)
+	GetGameStats(appID string, top int) (*models.GameStats, error)
+	GetProtonReleaseCounts(appID string) ([]models.ProtonReleaseCount, error)
+	ListReports(query ReportQuery) ([]models.Report, error)
+	GetGamesWithReports(query GamesWithReportsQuery) ([]models.MatchedGame, error)
+	GetTotalReportsCount() (int64, error)
+
+	// dataset stats
+	ComputeDatasetStats(top int) (*models.DatasetStats, error)
+	StreamGameRatings(fn func(appID string, ratings []models.ReportRating) error) error
+	SaveDatasetStats(stats *models.DatasetStats) error
+	GetDatasetStats() (*models.DatasetStats, error)
+
+	// regressions
+	GetVersionRatings(component string) ([]models.VersionRatings, error)
+	SaveRegressions(regressions []models.Regression, run models.RegressionRun) error
+	GetRegressionRun() (*models.RegressionRun, error)
+	ListRegressions(query RegressionQuery) ([]models.Regression, error)
+
+	// process status
+	GetLastProcessStatus() (*models.ProcessStatus, error)
+	GetLastProcessedData() (*models.ProcessStatus, error)
+	CreateProcessStatus(processStatus *models.ProcessStatus) error
+	UpdateProcessStatus(processStatus *models.ProcessStatus) error
+}
+
+var store Store = NewMongoStore()
+
+// SetStore sets the store used by the rest of the application
+func SetStore(s Store) {
+	store = s
+}
+
+// GetStore returns the store used by the rest of the application
+func GetStore() Store {
+	return store
+}
@@ -0,0 +1,447 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trsnaqe/protondb-api/pkg/hardware"
+	"github.com/trsnaqe/protondb-api/pkg/models"
+	"github.com/trsnaqe/protondb-api/pkg/proton"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The contract every Store implementation must keep, run against the in-memory store. A Mongo-backed
+// run would need a database, the same functions apply to it.
+
+func TestMemoryStoreContract(t *testing.T) {
+	testStoreContract(t, func() Store { return NewMemoryStore() })
+}
+
+func testStoreContract(t *testing.T, newStore func() Store) {
+	t.Run("games", func(t *testing.T) { testGames(t, newStore()) })
+	t.Run("reports", func(t *testing.T) { testReports(t, newStore()) })
+	t.Run("games with reports", func(t *testing.T) { testGamesWithReports(t, newStore()) })
+	t.Run("report filters", func(t *testing.T) { testReportFilters(t, newStore()) })
+	t.Run("distro version filter", func(t *testing.T) { testDistroVersionFilter(t, newStore()) })
+	t.Run("proton version sort", func(t *testing.T) { testProtonVersionSort(t, newStore()) })
+	t.Run("process status", func(t *testing.T) { testProcessStatus(t, newStore()) })
+	t.Run("dataset stats", func(t *testing.T) { testDatasetStats(t, newStore()) })
+	t.Run("regressions", func(t *testing.T) { testRegressions(t, newStore()) })
+}
+
+func alias(title string, count int64, day int) models.TitleAlias {
+	seen := time.Date(2023, 1, day, 0, 0, 0, 0, time.UTC)
+	return models.TitleAlias{Title: title, Count: count, FirstSeen: seen, LastSeen: seen}
+}
+
+func report(appID string, key int, normalized models.NormalizedReport) *models.Report {
+	normalized.AppID = appID
+	return models.NewReport(appID, map[string]interface{}{"appId": appID, "key": key}, "V2", normalized)
+}
+
+func testGames(t *testing.T, store Store) {
+	game, err := store.GetGameByAppID("10")
+	if err != nil || game != nil {
+		t.Fatalf("GetGameByAppID of a missing game = %v, %v, want nil, nil", game, err)
+	}
+
+	games, err := store.UpsertGames(map[string]string{"10": "Hades", "20": "DOOM"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 || *games["10"].Title != "Hades" || *games["20"].Title != "DOOM" {
+		t.Fatalf("UpsertGames returned %v", games)
+	}
+
+	// Upserting an existing game neither changes its title nor its aliases
+	hades := games["10"].ID
+	games, err = store.UpsertGames(map[string]string{"10": "Hades II"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if games["10"].ID != hades || *games["10"].Title != "Hades" || len(games["10"].Titles) != 0 {
+		t.Fatalf("UpsertGames of an existing game returned %v", games["10"])
+	}
+
+	if err := store.AddTitleAliases(map[string][]models.TitleAlias{"10": {alias("Hades", 2, 1)}}); err != nil {
+		t.Fatal(err)
+	}
+	// A title seen more often becomes the canonical title, the old one stays an alias
+	if err := store.AddTitleAliases(map[string][]models.TitleAlias{"10": {alias("Hades", 1, 2), alias("HADES", 4, 3)}}); err != nil {
+		t.Fatal(err)
+	}
+	game, err = store.GetGameByAppID("10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *game.Title != "HADES" {
+		t.Errorf("canonical title = %q, want HADES", *game.Title)
+	}
+	counts := map[string]int64{}
+	for _, alias := range game.Titles {
+		counts[alias.Title] = alias.Count
+	}
+	if counts["Hades"] != 3 || counts["HADES"] != 4 {
+		t.Errorf("alias counts = %v, want Hades 3 and HADES 4", counts)
+	}
+
+	total, err := store.GetTotalGamesCount()
+	if err != nil || total != 2 {
+		t.Errorf("GetTotalGamesCount = %d, %v, want 2", total, err)
+	}
+
+	first, err := store.ListGames(primitive.NilObjectID, 1)
+	if err != nil || len(first) != 1 {
+		t.Fatalf("ListGames first page = %v, %v", first, err)
+	}
+	second, err := store.ListGames(first[0].ID, 10)
+	if err != nil || len(second) != 1 || second[0].AppID == first[0].AppID {
+		t.Fatalf("ListGames second page = %v, %v", second, err)
+	}
+
+	byID, err := store.GetGamesByIDs([]primitive.ObjectID{first[0].ID})
+	if err != nil || len(byID) != 1 || byID[0].AppID != first[0].AppID {
+		t.Errorf("GetGamesByIDs = %v, %v", byID, err)
+	}
+}
+
+func testReports(t *testing.T, store Store) {
+	reports := []*models.Report{
+		report("10", 1, models.NormalizedReport{Rating: models.RatingGold}),
+		report("10", 2, models.NormalizedReport{Rating: models.RatingBorked}),
+		report("20", 3, models.NormalizedReport{Rating: models.RatingPlatinum}),
+	}
+	if err := store.InsertReports(reports); err != nil {
+		t.Fatal(err)
+	}
+	for _, report := range reports {
+		if report.ID.IsZero() {
+			t.Fatal("InsertReports left a report without an ID")
+		}
+	}
+
+	// Reports already stored are rejected by their hash, the others are still inserted
+	err := store.InsertReports([]*models.Report{
+		report("10", 1, models.NormalizedReport{Rating: models.RatingGold}),
+		report("20", 4, models.NormalizedReport{Rating: models.RatingSilver}),
+	})
+	var bulkErr *BulkWriteError
+	if !errors.As(err, &bulkErr) || len(bulkErr.Failed) != 1 || !errors.Is(bulkErr.Failed[0], ErrDuplicateReport) {
+		t.Fatalf("InsertReports of a duplicate = %v, want a BulkWriteError failing index 0 with ErrDuplicateReport", err)
+	}
+
+	total, err := store.GetTotalReportsCount()
+	if err != nil || total != 4 {
+		t.Errorf("GetTotalReportsCount = %d, %v, want 4", total, err)
+	}
+	counts, err := store.GetReportCounts()
+	if err != nil || counts["10"] != 2 || counts["20"] != 2 {
+		t.Errorf("GetReportCounts = %v, %v, want 10: 2 and 20: 2", counts, err)
+	}
+
+	byGame, err := store.GetReportsByGameID("10", "")
+	if err != nil || len(byGame) != 2 {
+		t.Errorf("GetReportsByGameID = %d reports, %v, want 2", len(byGame), err)
+	}
+	v1, err := store.GetReportsByGameID("10", "V1")
+	if err != nil || len(v1) != 0 {
+		t.Errorf("GetReportsByGameID V1 = %d reports, %v, want 0", len(v1), err)
+	}
+
+	ratings, err := store.GetReportRatings("10")
+	if err != nil || len(ratings) != 2 {
+		t.Errorf("GetReportRatings = %v, %v, want 2 ratings", ratings, err)
+	}
+
+	first, err := store.ListReports(ReportQuery{Limit: 3})
+	if err != nil || len(first) != 3 {
+		t.Fatalf("ListReports first page = %d reports, %v, want 3", len(first), err)
+	}
+	rest, err := store.ListReports(ReportQuery{After: first[2].ID, Limit: 3})
+	if err != nil || len(rest) != 1 {
+		t.Fatalf("ListReports after the first page = %d reports, %v, want 1", len(rest), err)
+	}
+	seen := map[primitive.ObjectID]bool{}
+	for _, report := range append(first, rest...) {
+		if seen[report.ID] {
+			t.Errorf("ListReports listed report %s twice", report.ID.Hex())
+		}
+		seen[report.ID] = true
+	}
+
+	byApp, err := store.ListReports(ReportQuery{AppIDs: []string{"20"}, Limit: 10})
+	if err != nil || len(byApp) != 2 {
+		t.Errorf("ListReports of game 20 = %d reports, %v, want 2", len(byApp), err)
+	}
+}
+
+func testGamesWithReports(t *testing.T, store Store) {
+	games, err := store.UpsertGames(map[string]string{"10": "Hades"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reports []*models.Report
+	for key := 1; key <= 3; key++ {
+		reports = append(reports, report("10", key, models.NormalizedReport{Rating: models.RatingGold}))
+	}
+	if err := store.InsertReports(reports); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		reportsPerGame int
+		version        string
+		filter         ReportFilter
+		want           int
+	}{
+		{"capped", 2, "", ReportFilter{}, 2},
+		{"uncapped", 0, "", ReportFilter{}, 3},
+		{"other version", 0, "V1", ReportFilter{}, 0},
+		{"filtered", 0, "", ReportFilter{Ratings: []string{models.RatingPlatinum}}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matched, err := store.GetGamesWithReports(GamesWithReportsQuery{
+				GameIDs:        []primitive.ObjectID{games["10"].ID},
+				Version:        test.version,
+				Filter:         test.filter,
+				ReportsPerGame: test.reportsPerGame,
+			})
+			if err != nil || len(matched) != 1 {
+				t.Fatalf("GetGamesWithReports = %v, %v, want 1 game", matched, err)
+			}
+			if len(matched[0].MatchedReports) != test.want {
+				t.Errorf("GetGamesWithReports = %d reports, want %d", len(matched[0].MatchedReports), test.want)
+			}
+		})
+	}
+}
+
+func testReportFilters(t *testing.T, store Store) {
+	err := store.InsertReports([]*models.Report{
+		report("10", 1, models.NormalizedReport{Rating: models.RatingGold, GPUVendor: "nvidia", Timestamp: time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)}),
+		report("10", 2, models.NormalizedReport{Rating: models.RatingBorked, GPUVendor: "amd", Timestamp: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)}),
+		report("10", 3, models.NormalizedReport{Rating: models.RatingPlatinum, GPUVendor: "amd", Timestamp: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter ReportFilter
+		want   int
+	}{
+		{"no filter", ReportFilter{}, 3},
+		{"gpu vendor", ReportFilter{GPUVendor: "AMD"}, 2},
+		{"ratings", ReportFilter{Ratings: []string{models.RatingGold, models.RatingPlatinum}}, 2},
+		{"time range", ReportFilter{From: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}, 2},
+		{"combined", ReportFilter{GPUVendor: "amd", To: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)}, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reports, err := store.ListReports(ReportQuery{Filter: test.filter, Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(reports) != test.want {
+				t.Errorf("got %d reports, want %d", len(reports), test.want)
+			}
+		})
+	}
+}
+
+func testDistroVersionFilter(t *testing.T, store Store) {
+	var reports []*models.Report
+	for i, os := range []string{"Ubuntu 22.04.3 LTS", "Ubuntu 22.04", "Ubuntu 22.10", "Ubuntu 2.04"} {
+		reports = append(reports, report("10", i, models.NormalizedReport{OS: os, Hardware: hardware.Parse("", "", "", os, "", "")}))
+	}
+	if err := store.InsertReports(reports); err != nil {
+		t.Fatal(err)
+	}
+
+	var filter ReportFilter
+	if err := filter.SetDistroVersion("22.04"); err != nil {
+		t.Fatal(err)
+	}
+	matched, err := store.ListReports(ReportQuery{Filter: filter, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matched) != 2 {
+		t.Errorf("distro version 22.04 matched %d reports, want 22.04 and 22.04.3", len(matched))
+	}
+
+	for _, invalid := range []string{"", "jammy", "22.04(", "22..04"} {
+		if err := filter.SetDistroVersion(invalid); err == nil {
+			t.Errorf("SetDistroVersion(%q) succeeded, want an error", invalid)
+		}
+	}
+}
+
+func testProtonVersionSort(t *testing.T, store Store) {
+	var reports []*models.Report
+	for i, version := range []string{"Proton 9.0-2", "GE-Proton8-25", "Proton 10.0-1", "Experimental", "Proton 8.0-3"} {
+		reports = append(reports, report("10", i, models.NormalizedReport{ProtonVersion: version, Proton: proton.Parse(version)}))
+	}
+	if err := store.InsertReports(reports); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Experimental", "Proton 8.0-3", "GE-Proton8-25", "Proton 9.0-2", "Proton 10.0-1"}
+	sort, err := ParseReportSort("protonVersion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two reports per page, so the cursor carries the sort value across pages
+	var got []string
+	query := ReportQuery{Sort: sort, Limit: 2}
+	for {
+		page, err := store.ListReports(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, report := range page {
+			got = append(got, report.Normalized.ProtonVersion)
+		}
+		last := page[len(page)-1]
+		query.After, query.AfterValue = last.ID, sort.Value(last)
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("sorted Proton versions = %v, want %v", got, want)
+	}
+}
+
+func testProcessStatus(t *testing.T, store Store) {
+	status, err := store.GetLastProcessStatus()
+	if err != nil || status != nil {
+		t.Fatalf("GetLastProcessStatus of a new store = %v, %v, want nil, nil", status, err)
+	}
+
+	status = &models.ProcessStatus{LastProcessedFile: "reports_jan1_2023.tar.gz", LastCommittedIndex: -1}
+	if err := store.CreateProcessStatus(status); err != nil {
+		t.Fatal(err)
+	}
+	status.CurrentFile = "reports_feb1_2023.tar.gz"
+	status.LastCommittedIndex = 999
+	if err := store.UpdateProcessStatus(status); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := store.GetLastProcessStatus()
+	if err != nil || stored == nil {
+		t.Fatalf("GetLastProcessStatus = %v, %v", stored, err)
+	}
+	if stored.CurrentFile != status.CurrentFile || stored.LastCommittedIndex != 999 || stored.LastProcessedFile != status.LastProcessedFile {
+		t.Errorf("GetLastProcessStatus = %+v, want %+v", stored, status)
+	}
+}
+
+func testDatasetStats(t *testing.T, store Store) {
+	stats, err := store.GetDatasetStats()
+	if err != nil || stats != nil {
+		t.Fatalf("GetDatasetStats of a new store = %v, %v, want nil, nil", stats, err)
+	}
+
+	if _, err := store.UpsertGames(map[string]string{"10": "Hades"}); err != nil {
+		t.Fatal(err)
+	}
+	err = store.InsertReports([]*models.Report{
+		report("10", 1, models.NormalizedReport{Rating: models.RatingGold, Timestamp: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)}),
+		report("10", 2, models.NormalizedReport{Rating: models.RatingGold, Timestamp: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	computed, err := store.ComputeDatasetStats(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(computed.ReportsPerMonth) != 2 || computed.ReportsPerMonth[0].Value != "2023-05" {
+		t.Errorf("ReportsPerMonth = %v, want 2023-05 and 2023-06", computed.ReportsPerMonth)
+	}
+	if len(computed.TopGames) != 1 || computed.TopGames[0].Title != "Hades" || computed.TopGames[0].Count != 2 {
+		t.Errorf("TopGames = %v, want Hades with 2 reports", computed.TopGames)
+	}
+
+	if err := store.SaveDatasetStats(computed); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = store.GetDatasetStats()
+	if err != nil || stats == nil || !stats.ComputedAt.Equal(computed.ComputedAt) {
+		t.Errorf("GetDatasetStats = %v, %v, want the saved stats", stats, err)
+	}
+}
+
+func testRegressions(t *testing.T, store Store) {
+	run, err := store.GetRegressionRun()
+	if err != nil || run != nil {
+		t.Fatalf("GetRegressionRun of a new store = %v, %v, want nil, nil", run, err)
+	}
+
+	regressions := []models.Regression{
+		{AppID: "10", Component: models.RegressionProton, Group: "official", Drop: 0.5, FromReports: 20, ToReports: 20},
+		{AppID: "20", Component: models.RegressionDriver, Group: "nvidia", Drop: 0.3, FromReports: 5, ToReports: 30},
+		{AppID: "10", Component: models.RegressionKernel, Drop: 0.2, FromReports: 15, ToReports: 15},
+	}
+	if err := store.SaveRegressions(regressions, models.RegressionRun{Regressions: len(regressions)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Regressions are listed in the order they were saved, a page at a time
+	first, err := store.ListRegressions(RegressionQuery{Limit: 2})
+	if err != nil || len(first) != 2 || first[0].Drop != 0.5 || first[1].Drop != 0.3 {
+		t.Fatalf("ListRegressions first page = %v, %v", first, err)
+	}
+	rest, err := store.ListRegressions(RegressionQuery{After: first[1].ID})
+	if err != nil || len(rest) != 1 || rest[0].Drop != 0.2 {
+		t.Fatalf("ListRegressions after the first page = %v, %v", rest, err)
+	}
+
+	tests := []struct {
+		name  string
+		query RegressionQuery
+		want  int
+	}{
+		{"app", RegressionQuery{AppID: "10"}, 2},
+		{"component", RegressionQuery{Component: models.RegressionDriver}, 1},
+		{"group", RegressionQuery{Group: "official"}, 1},
+		{"min reports on both versions", RegressionQuery{MinReports: 10}, 2},
+		{"min drop", RegressionQuery{MinDrop: 0.3}, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			listed, err := store.ListRegressions(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(listed) != test.want {
+				t.Errorf("got %d regressions, want %d", len(listed), test.want)
+			}
+		})
+	}
+
+	// Saving replaces the previous regressions and run
+	previous, err := store.GetRegressionRun()
+	if err != nil || previous == nil {
+		t.Fatalf("GetRegressionRun = %v, %v", previous, err)
+	}
+	if err := store.SaveRegressions(regressions[:1], models.RegressionRun{Regressions: 1}); err != nil {
+		t.Fatal(err)
+	}
+	run, err = store.GetRegressionRun()
+	if err != nil || run == nil || run.Regressions != 1 || run.RunID == previous.RunID {
+		t.Fatalf("GetRegressionRun = %v, %v, want the last run", run, err)
+	}
+	listed, err := store.ListRegressions(RegressionQuery{})
+	if err != nil || len(listed) != 1 || listed[0].RunID != run.RunID {
+		t.Errorf("ListRegressions after saving again = %v, %v, want 1 regression of the last run", listed, err)
+	}
+}